@@ -0,0 +1,217 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/elastos/Elastos.ELA.SideChain/database"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	. "github.com/elastos/Elastos.ELA/common"
+)
+
+const (
+	IX_Address_Tx     = 0x96
+	IX_Address_Height = 0x97
+
+	persistAddressIndex  = "persistAddressIndex"
+	rollbackAddressIndex = "rollbackAddressIndex"
+
+	// addressBatchSize is the number of blocks which transactions are
+	// committed in a batch when indexing the existing blocks.
+	addressBatchSize = 1000
+)
+
+// addressHeightKey is the key of the height of the last block in the address
+// index.
+var addressHeightKey = []byte{IX_Address_Height}
+
+// AddressTx is a transaction involving an address, the address is paid by
+// the outputs or spent by the inputs of the transaction.
+type AddressTx struct {
+	TxID   Uint256
+	Height uint32
+}
+
+func addressTxPrefix(programHash Uint168) []byte {
+	return append([]byte{IX_Address_Tx}, programHash.Bytes()...)
+}
+
+// addressTxKey returns the key of the transaction in the address index, the
+// height is big-endian so transactions are iterated in height order.
+func addressTxKey(programHash Uint168, height uint32, txID Uint256) []byte {
+	key := bytes.NewBuffer(addressTxPrefix(programHash))
+	var h [4]byte
+	binary.BigEndian.PutUint32(h[:], height)
+	key.Write(h[:])
+	txID.Serialize(key)
+	return key.Bytes()
+}
+
+// GetAddressTxs returns the transactions involving the address in height
+// order.  The blocks persisted before the index existed are indexed when the
+// store is opened, so all the transactions of the address are returned.
+func (c *TokenChainStore) GetAddressTxs(programHash Uint168) ([]AddressTx, error) {
+	if !c.addressIndex {
+		return nil, errors.New("address index is not enabled")
+	}
+	var txs []AddressTx
+
+	prefix := addressTxPrefix(programHash)
+	iter := c.NewIterator(prefix)
+	defer iter.Release()
+	for iter.Next() {
+		key := iter.Key()[len(prefix):]
+		if len(key) != 4+32 {
+			continue
+		}
+		var tx AddressTx
+		tx.Height = binary.BigEndian.Uint32(key)
+		copy(tx.TxID[:], key[4:])
+		txs = append(txs, tx)
+	}
+
+	return txs, nil
+}
+
+// blockAddresses returns the program hashes involved in each transaction of
+// the block.  The referenced outputs are looked up in the block first, since
+// the transactions of the block are not in the store yet while persisting.
+func (c *TokenChainStore) blockAddresses(b *types.Block) (map[Uint256]map[Uint168]struct{}, error) {
+	blockTxs := make(map[Uint256]*types.Transaction, len(b.Transactions))
+	for _, txn := range b.Transactions {
+		blockTxs[txn.Hash()] = txn
+	}
+
+	addresses := make(map[Uint256]map[Uint168]struct{}, len(b.Transactions))
+	for _, txn := range b.Transactions {
+		programHashes := make(map[Uint168]struct{})
+		for _, output := range txn.Outputs {
+			programHashes[output.ProgramHash] = struct{}{}
+		}
+		for _, input := range txn.Inputs {
+			if txn.IsCoinBaseTx() || txn.IsRechargeToSideChainTx() {
+				break
+			}
+			reference, ok := blockTxs[input.Previous.TxID]
+			if !ok {
+				var err error
+				reference, _, err = c.GetTransaction(input.Previous.TxID)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if int(input.Previous.Index) < len(reference.Outputs) {
+				programHashes[reference.Outputs[input.Previous.Index].ProgramHash] = struct{}{}
+			}
+		}
+		addresses[txn.Hash()] = programHashes
+	}
+	return addresses, nil
+}
+
+// addressIndexHeight returns the height of the last block in the address
+// index, ok is false if no block is indexed.
+func (c *TokenChainStore) addressIndexHeight() (height uint32, ok bool) {
+	data, err := c.Get(addressHeightKey)
+	if err != nil || len(data) != 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(data), true
+}
+
+func putAddressIndexHeight(batch database.Batch, height uint32) error {
+	var h [4]byte
+	binary.LittleEndian.PutUint32(h[:], height)
+	return batch.Put(addressHeightKey, h[:])
+}
+
+// indexAddresses adds the transactions of the blocks persisted before the
+// address index existed into the index, from the block after the last
+// indexed one.  Blocks are committed in batches with the indexed height, so
+// an interrupted indexing resumes from the last batch.
+func (c *TokenChainStore) indexAddresses() error {
+	bestHeight := c.GetHeight()
+	var start uint32
+	if height, ok := c.addressIndexHeight(); ok {
+		if height >= bestHeight {
+			return nil
+		}
+		start = height + 1
+	}
+
+	batch := c.NewBatch()
+	pending := 0
+	for height := start; height <= bestHeight; height++ {
+		hash, err := c.GetBlockHash(height)
+		if err != nil {
+			return err
+		}
+		block, err := c.GetBlock(hash)
+		if err != nil {
+			return err
+		}
+		if err := c.persistAddressIndex(batch, block); err != nil {
+			return err
+		}
+		if pending++; pending >= addressBatchSize || height == bestHeight {
+			if err := batch.Commit(); err != nil {
+				return err
+			}
+			batch = c.NewBatch()
+			pending = 0
+		}
+	}
+	return nil
+}
+
+// dropAddressIndex removes the address index built before it is disabled,
+// since it is not updated while disabled, it is rebuilt from the genesis block
+// once it is enabled again.
+func (c *TokenChainStore) dropAddressIndex() error {
+	if _, ok := c.addressIndexHeight(); !ok {
+		return nil
+	}
+
+	iter := c.NewIterator([]byte{IX_Address_Tx})
+	defer iter.Release()
+	batch := c.NewBatch()
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	batch.Delete(addressHeightKey)
+	return batch.Commit()
+}
+
+func (c *TokenChainStore) persistAddressIndex(batch database.Batch, b *types.Block) error {
+	addresses, err := c.blockAddresses(b)
+	if err != nil {
+		return err
+	}
+	for txID, programHashes := range addresses {
+		for programHash := range programHashes {
+			key := addressTxKey(programHash, b.Header.Height, txID)
+			if err := batch.Put(key, []byte{}); err != nil {
+				return err
+			}
+		}
+	}
+	return putAddressIndexHeight(batch, b.Header.Height)
+}
+
+func (c *TokenChainStore) rollbackAddressIndex(batch database.Batch, b *types.Block) error {
+	addresses, err := c.blockAddresses(b)
+	if err != nil {
+		return err
+	}
+	for txID, programHashes := range addresses {
+		for programHash := range programHashes {
+			batch.Delete(addressTxKey(programHash, b.Header.Height, txID))
+		}
+	}
+	if b.Header.Height == 0 {
+		batch.Delete(addressHeightKey)
+		return nil
+	}
+	return putAddressIndexHeight(batch, b.Header.Height-1)
+}
@@ -0,0 +1,114 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/elastos/Elastos.ELA.SideChain/database"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	. "github.com/elastos/Elastos.ELA/common"
+)
+
+const (
+	IX_Asset_Height = 0x95
+
+	persistAssetIndex  = "persistAssetIndex"
+	rollbackAssetIndex = "rollbackAssetIndex"
+
+	// AssetCursorSize is the size of the cursor of the registered assets, the
+	// big-endian registration height followed by the asset id.
+	AssetCursorSize = 4 + 32
+)
+
+// assetHeightKey returns the key of the asset in the registration height
+// index, the height is big-endian so assets are iterated in height order.
+func assetHeightKey(height uint32, assetID Uint256) []byte {
+	key := make([]byte, 1+AssetCursorSize)
+	key[0] = IX_Asset_Height
+	binary.BigEndian.PutUint32(key[1:], height)
+	copy(key[5:], assetID[:])
+	return key
+}
+
+// registeredAssetID returns the id of the asset registered by the transaction.
+func (c *TokenChainStore) registeredAssetID(txn *types.Transaction) Uint256 {
+	if c.systemAssetID.IsEqual(txn.Hash()) {
+		return txn.Hash()
+	}
+	return txn.Payload.(*types.PayloadRegisterAsset).Asset.Hash()
+}
+
+// ListAssets iterates the registered assets in the order of registration
+// height and asset id.  The iteration starts after the cursor if it is not
+// empty, and stops if fn returns false.
+func (c *TokenChainStore) ListAssets(cursor []byte,
+	fn func(cursor []byte, assetID Uint256, asset *AssetInfo) bool) error {
+	iter := c.NewIterator([]byte{IX_Asset_Height})
+	defer iter.Release()
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != 1+AssetCursorSize {
+			continue
+		}
+		if len(cursor) > 0 && bytes.Compare(key[1:], cursor) <= 0 {
+			continue
+		}
+
+		var assetID Uint256
+		copy(assetID[:], key[5:])
+		asset, err := c.GetAsset(assetID)
+		if err != nil {
+			return err
+		}
+		next := make([]byte, AssetCursorSize)
+		copy(next, key[1:])
+		if !fn(next, assetID, asset) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// indexAssets adds the assets registered before the registration height
+// index existed into the index.
+func (c *TokenChainStore) indexAssets() error {
+	batch := c.NewBatch()
+	missing := false
+	for assetID, asset := range c.GetAssets() {
+		key := assetHeightKey(asset.Height, assetID)
+		if _, err := c.Get(key); err == nil {
+			continue
+		}
+		if err := batch.Put(key, []byte{}); err != nil {
+			return err
+		}
+		missing = true
+	}
+	if !missing {
+		return nil
+	}
+	return batch.Commit()
+}
+
+func (c *TokenChainStore) persistAssetIndex(batch database.Batch, b *types.Block) error {
+	for _, txn := range b.Transactions {
+		if txn.TxType != types.RegisterAsset {
+			continue
+		}
+		key := assetHeightKey(b.Header.Height, c.registeredAssetID(txn))
+		if err := batch.Put(key, []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *TokenChainStore) rollbackAssetIndex(batch database.Batch, b *types.Block) error {
+	for _, txn := range b.Transactions {
+		if txn.TxType != types.RegisterAsset {
+			continue
+		}
+		batch.Delete(assetHeightKey(b.Header.Height, c.registeredAssetID(txn)))
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA.SideChain/database"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	. "github.com/elastos/Elastos.ELA/common"
+
+	"github.com/btcsuite/btcutil/gcs"
+)
+
+const (
+	IX_Block_Filter  = 0x92
+	IX_Filter_Header = 0x93
+	IX_Filter_Height = 0x98
+
+	// BlockFilterType is the type of the block filter, only the basic type
+	// is supported now.
+	BlockFilterType uint8 = 0
+
+	// BlockFilterP and BlockFilterM are the Golomb-Rice coding parameters of
+	// the block filter, same as BIP158.
+	BlockFilterP uint8  = 19
+	BlockFilterM uint64 = 784931
+
+	persistBlockFilter  = "persistBlockFilter"
+	rollbackBlockFilter = "rollbackBlockFilter"
+
+	// filterBatchSize is the number of blocks which filters are committed in
+	// a batch when building filters of the existing blocks.
+	filterBatchSize = 1000
+)
+
+// filterHeightKey is the key of the height of the last block which filter is
+// persisted.
+var filterHeightKey = []byte{IX_Filter_Height}
+
+// BlockFilterKey returns the SipHash key of the block filter, which is the
+// first 16 bytes of the block hash.
+func BlockFilterKey(blockHash Uint256) [gcs.KeySize]byte {
+	var key [gcs.KeySize]byte
+	copy(key[:], blockHash[:])
+	return key
+}
+
+// BlockFilterElements returns the elements of the block filter, which are the
+// program hashes and asset IDs of outputs, and outpoints spent by inputs.
+func BlockFilterElements(b *types.Block) [][]byte {
+	var elements [][]byte
+	seen := make(map[string]bool)
+	add := func(e []byte) {
+		if !seen[string(e)] {
+			seen[string(e)] = true
+			elements = append(elements, e)
+		}
+	}
+
+	for _, txn := range b.Transactions {
+		for _, output := range txn.Outputs {
+			add(output.ProgramHash.Bytes())
+			add(output.AssetID.Bytes())
+		}
+		if txn.IsCoinBaseTx() {
+			continue
+		}
+		for _, input := range txn.Inputs {
+			buf := new(bytes.Buffer)
+			input.Previous.Serialize(buf)
+			add(buf.Bytes())
+		}
+	}
+	return elements
+}
+
+// BuildBlockFilter builds the serialized block filter of the block.
+func BuildBlockFilter(b *types.Block) ([]byte, error) {
+	filter, err := gcs.BuildGCSFilter(BlockFilterP, BlockFilterM,
+		BlockFilterKey(b.Hash()), BlockFilterElements(b))
+	if err != nil {
+		return nil, err
+	}
+	return filter.NBytes()
+}
+
+// MatchBlockFilter returns if any of the elements may be in the serialized
+// block filter of the block with the given hash.
+func MatchBlockFilter(blockHash Uint256, filter []byte, elements [][]byte) (bool, error) {
+	f, err := gcs.FromNBytes(BlockFilterP, BlockFilterM, filter)
+	if err != nil {
+		return false, err
+	}
+	return f.MatchAny(BlockFilterKey(blockHash), elements)
+}
+
+// FilterHash returns the hash of the serialized block filter.
+func FilterHash(filter []byte) Uint256 {
+	return Sha256D(filter)
+}
+
+// FilterHeader returns the filter header which commits to the filter and the
+// previous filter header.
+func FilterHeader(filter []byte, prevHeader Uint256) Uint256 {
+	filterHash := FilterHash(filter)
+	return Sha256D(append(filterHash[:], prevHeader[:]...))
+}
+
+func blockFilterKey(prefix byte, blockHash Uint256) []byte {
+	return append([]byte{prefix}, blockHash.Bytes()...)
+}
+
+// GetBlockFilter returns the serialized block filter of the block.
+func (c *TokenChainStore) GetBlockFilter(blockHash Uint256) ([]byte, error) {
+	return c.Get(blockFilterKey(IX_Block_Filter, blockHash))
+}
+
+// GetFilterHeader returns the filter header of the block.
+func (c *TokenChainStore) GetFilterHeader(blockHash Uint256) (Uint256, error) {
+	data, err := c.Get(blockFilterKey(IX_Filter_Header, blockHash))
+	if err != nil {
+		return Uint256{}, err
+	}
+	header, err := Uint256FromBytes(data)
+	if err != nil {
+		return Uint256{}, err
+	}
+	return *header, nil
+}
+
+// putBlockFilter builds the block filter of the block and puts it with the
+// filter header into the batch, and returns the filter header.
+func putBlockFilter(batch database.Batch, b *types.Block, prevHeader Uint256) (Uint256, error) {
+	filter, err := BuildBlockFilter(b)
+	if err != nil {
+		return Uint256{}, err
+	}
+	header := FilterHeader(filter, prevHeader)
+
+	blockHash := b.Hash()
+	if err := batch.Put(blockFilterKey(IX_Block_Filter, blockHash), filter); err != nil {
+		return Uint256{}, err
+	}
+	if err := batch.Put(blockFilterKey(IX_Filter_Header, blockHash), header.Bytes()); err != nil {
+		return Uint256{}, err
+	}
+	return header, nil
+}
+
+// filterIndexHeight returns the height of the last block which filter is
+// persisted, ok is false if no filter is persisted with the height.
+func (c *TokenChainStore) filterIndexHeight() (height uint32, ok bool) {
+	data, err := c.Get(filterHeightKey)
+	if err != nil || len(data) != 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(data), true
+}
+
+func putFilterIndexHeight(batch database.Batch, height uint32) error {
+	var h [4]byte
+	binary.LittleEndian.PutUint32(h[:], height)
+	return batch.Put(filterHeightKey, h[:])
+}
+
+// indexBlockFilters builds the filters of blocks persisted before filters are
+// supported from the genesis block, so every filter header commits to the
+// whole filter chain.  Blocks are committed in batches with the indexed
+// height, so the filters are built once and an interrupted indexing resumes
+// from the last batch.
+func (c *TokenChainStore) indexBlockFilters() error {
+	bestHeight := c.GetHeight()
+	var start uint32
+	var prevHeader Uint256
+	if height, ok := c.filterIndexHeight(); ok {
+		if height >= bestHeight {
+			return nil
+		}
+		hash, err := c.GetBlockHash(height)
+		if err != nil {
+			return err
+		}
+		if prevHeader, err = c.GetFilterHeader(hash); err != nil {
+			return err
+		}
+		start = height + 1
+	}
+
+	batch := c.NewBatch()
+	pending := 0
+	for height := start; height <= bestHeight; height++ {
+		hash, err := c.GetBlockHash(height)
+		if err != nil {
+			return err
+		}
+		if header, err := c.GetFilterHeader(hash); err == nil {
+			prevHeader = header
+		} else {
+			block, err := c.GetBlock(hash)
+			if err != nil {
+				return err
+			}
+			if prevHeader, err = putBlockFilter(batch, block, prevHeader); err != nil {
+				return err
+			}
+		}
+		if pending++; pending >= filterBatchSize || height == bestHeight {
+			if err := putFilterIndexHeight(batch, height); err != nil {
+				return err
+			}
+			if err := batch.Commit(); err != nil {
+				return err
+			}
+			batch = c.NewBatch()
+			pending = 0
+		}
+	}
+	return nil
+}
+
+// persistBlockFilter builds and persists the block filter and filter header.
+// The previous filter header of the genesis block is the empty hash.
+func (c *TokenChainStore) persistBlockFilter(batch database.Batch, b *types.Block) error {
+	var prevHeader Uint256
+	if b.Header.Height > 0 {
+		var err error
+		prevHeader, err = c.GetFilterHeader(b.Header.Previous)
+		if err != nil {
+			return fmt.Errorf("filter header of previous block %s not found", b.Header.Previous)
+		}
+	}
+	if _, err := putBlockFilter(batch, b, prevHeader); err != nil {
+		return err
+	}
+	return putFilterIndexHeight(batch, b.Header.Height)
+}
+
+func (c *TokenChainStore) rollbackBlockFilter(batch database.Batch, b *types.Block) error {
+	blockHash := b.Hash()
+	batch.Delete(blockFilterKey(IX_Block_Filter, blockHash))
+	batch.Delete(blockFilterKey(IX_Filter_Header, blockHash))
+	if b.Header.Height == 0 {
+		batch.Delete(filterHeightKey)
+		return nil
+	}
+	return putFilterIndexHeight(batch, b.Header.Height-1)
+}
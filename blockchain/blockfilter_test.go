@@ -0,0 +1,92 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBlocks returns a chain of blocks from the genesis block, each block
+// transfers an output of the previous one.
+func testBlocks(count int) []*types.Block {
+	blocks := []*types.Block{params.GenesisBlock}
+	prevTx := params.GenesisBlock.Transactions[0]
+	for i := 1; i < count; i++ {
+		header := params.GenesisBlock.Header
+		header.Height = uint32(i)
+		header.Previous = blocks[i-1].Hash()
+		tx := &types.Transaction{
+			TxType:  types.TransferAsset,
+			Payload: &types.PayloadTransferAsset{},
+			Inputs:  []*types.Input{{Previous: types.OutPoint{TxID: prevTx.Hash()}}},
+			Outputs: []*types.Output{{
+				AssetID:     params.ElaAssetId,
+				Value:       common.Fixed64(i),
+				ProgramHash: common.Uint168{0x21, byte(i)},
+			}},
+		}
+		blocks = append(blocks, &types.Block{Header: header, Transactions: []*types.Transaction{tx}})
+		prevTx = tx
+	}
+	return blocks
+}
+
+func TestBlockFilterMatch(t *testing.T) {
+	blocks := testBlocks(3)
+	b := blocks[2]
+	filter, err := BuildBlockFilter(b)
+	assert.NoError(t, err)
+
+	for _, element := range BlockFilterElements(b) {
+		matched, err := MatchBlockFilter(b.Hash(), filter, [][]byte{element})
+		assert.NoError(t, err)
+		assert.True(t, matched)
+	}
+	matched, err := MatchBlockFilter(b.Hash(), filter, [][]byte{common.Uint168{0x21, 0x01}.Bytes()})
+	assert.NoError(t, err)
+	assert.False(t, matched)
+}
+
+func TestFilterHeaderChain(t *testing.T) {
+	blocks := testBlocks(5)
+
+	// The headers built by the node from the genesis block.
+	var prevHeader common.Uint256
+	var headers, filterHashes []common.Uint256
+	for _, b := range blocks {
+		filter, err := BuildBlockFilter(b)
+		assert.NoError(t, err)
+		header := FilterHeader(filter, prevHeader)
+		headers = append(headers, header)
+		filterHashes = append(filterHashes, FilterHash(filter))
+		prevHeader = header
+	}
+
+	// A client connects the filter hashes of a cfheaders message to the
+	// previous filter header it knows.
+	for start := range blocks {
+		var prev common.Uint256
+		if start > 0 {
+			prev = headers[start-1]
+		}
+		for i, filterHash := range filterHashes[start:] {
+			prev = common.Sha256D(append(filterHash[:], prev[:]...))
+			assert.Equal(t, headers[start+i], prev)
+		}
+	}
+
+	// Every header commits to all the filters before it.
+	filter, err := BuildBlockFilter(blocks[2])
+	assert.NoError(t, err)
+	prev := FilterHeader(append(filter, 0x00), headers[1])
+	for i := 3; i < len(blocks); i++ {
+		filter, err := BuildBlockFilter(blocks[i])
+		assert.NoError(t, err)
+		prev = FilterHeader(filter, prev)
+		assert.NotEqual(t, headers[i], prev)
+	}
+}
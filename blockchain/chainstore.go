@@ -2,13 +2,16 @@ package blockchain
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
-	"math/big"
-	"strings"
+	"sort"
+	"strconv"
+	"sync"
 
 	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
 	"github.com/elastos/Elastos.ELA.SideChain/blockchain"
 	"github.com/elastos/Elastos.ELA.SideChain/config"
 	"github.com/elastos/Elastos.ELA.SideChain/database"
@@ -18,9 +21,26 @@ import (
 
 const IX_Unspent_UTXO = 0x91
 
+const (
+	// unspentKeySize is the size of the key of the unspent index without the
+	// prefix, the program hash, the asset id and the little-endian height.
+	unspentKeySize = 21 + 32 + 4
+
+	// UnspentCursorSize is the size of the cursor of an unspent output, the
+	// key of the output in the unspent index followed by the txid and the
+	// big-endian index of the output.
+	UnspentCursorSize = unspentKeySize + 32 + 4
+)
+
 type TokenChainStore struct {
 	*blockchain.ChainStore
 	systemAssetID Uint256
+	assetParams   *params.AssetParams
+	addressIndex  bool
+	listeners     []BlockListener
+
+	statsMtx  sync.Mutex
+	utxoStats *UTXOSetStats
 }
 
 type Config struct {
@@ -38,6 +58,11 @@ func (a *AssetInfo) Serialize(w io.Writer) error {
 		return err
 	}
 
+	// ELA is registered in the genesis block without height, keep the same
+	// layout as Deserialize.
+	if a.Asset.Name == "ELA" {
+		return nil
+	}
 	if err := WriteUint32(w, a.Height); err != nil {
 		return err
 	}
@@ -57,35 +82,47 @@ func (a *AssetInfo) Deserialize(r io.Reader) error {
 	return nil
 }
 
-type utxo struct {
+type UTXO struct {
 	TxID    Uint256
 	Index   uint32
 	AssetID Uint256
 	Value   []byte
 }
 
-func (u *utxo) ValueString() string {
-	maxPrecision := 18
+// ValueString returns the value as an exact decimal string, token values are
+// formatted in the given precision of the asset.
+func (u *UTXO) ValueString(precision byte) string {
 	if u.AssetID == types.GetSystemAssetId() {
-		number, _ := Fixed64FromBytes(u.Value)
-		return number.String()
-	} else {
-		total := new(big.Int).SetBytes(u.Value).String()
-		if total == "0" {
-			return total
+		number, err := Fixed64FromBytes(u.Value)
+		if err != nil {
+			return ""
 		}
-		numberLength := len(total)
-		if numberLength >= maxPrecision+1 {
-			fractionalPart := total[numberLength-maxPrecision:]
-			integerPart := total[:numberLength-maxPrecision]
-			return integerPart + "." + fractionalPart
-		} else {
-			return "0." + strings.Repeat("0", maxPrecision-len(total)) + total
+		return number.String()
+	}
+	amount, err := core.TokenAmountFromBytes(u.Value)
+	if err != nil {
+		return ""
+	}
+	return amount.Format(precision)
+}
+
+// UnitsString returns the value in base units as a decimal string.
+func (u *UTXO) UnitsString() string {
+	if u.AssetID == types.GetSystemAssetId() {
+		number, err := Fixed64FromBytes(u.Value)
+		if err != nil {
+			return ""
 		}
+		return strconv.FormatInt(int64(*number), 10)
+	}
+	amount, err := core.TokenAmountFromBytes(u.Value)
+	if err != nil {
+		return ""
 	}
+	return amount.Int().String()
 }
 
-func (u *utxo) Serialize(w io.Writer) error {
+func (u *UTXO) Serialize(w io.Writer) error {
 	if err := u.TxID.Serialize(w); err != nil {
 		return err
 	}
@@ -104,7 +141,7 @@ func (u *utxo) Serialize(w io.Writer) error {
 	return nil
 }
 
-func (u *utxo) Deserialize(r io.Reader) error {
+func (u *UTXO) Deserialize(r io.Reader) error {
 	var err error
 	if err := u.TxID.Deserialize(r); err != nil {
 		return err
@@ -121,12 +158,15 @@ func (u *utxo) Deserialize(r io.Reader) error {
 
 	u.Value, err = ReadVarBytes(r, core.MaxTokenValueDataSize, "value")
 	if err != nil {
-		return nil
+		return err
 	}
 	return nil
 }
 
-func NewChainStore(genesisBlock *types.Block, assetID Uint256, dataPath string) (*TokenChainStore, error) {
+// NewChainStore opens the chain store, the address index is only built if
+// addressIndex is set, since only the wallet looks up transactions by address.
+func NewChainStore(genesisBlock *types.Block, assetID Uint256, assetParams *params.AssetParams,
+	dataPath string, addressIndex bool) (*TokenChainStore, error) {
 	chainStore, err := blockchain.NewChainStore(dataPath, genesisBlock)
 	if err != nil {
 		return nil, err
@@ -135,14 +175,41 @@ func NewChainStore(genesisBlock *types.Block, assetID Uint256, dataPath string)
 	store := &TokenChainStore{
 		ChainStore:    chainStore,
 		systemAssetID: assetID,
+		assetParams:   assetParams,
+		addressIndex:  addressIndex,
 	}
 	store.RegisterFunctions(true, blockchain.StoreFuncNames.PersistUnspendUTXOs, store.persistUnspendUTXOs)
 	store.RegisterFunctions(true, blockchain.StoreFuncNames.PersistTransactions, store.persistTransactions)
 	store.RegisterFunctions(true, blockchain.StoreFuncNames.PersistUnspend, store.persistUnspend)
+	store.RegisterFunctions(true, persistBlockFilter, store.persistBlockFilter)
+	store.RegisterFunctions(true, persistOutputMemos, store.persistOutputMemos)
+	store.RegisterFunctions(true, persistAssetIndex, store.persistAssetIndex)
 
 	store.RegisterFunctions(false, blockchain.StoreFuncNames.RollbackUnspendUTXOs, store.rollbackUnspendUTXOs)
 	store.RegisterFunctions(false, blockchain.StoreFuncNames.RollbackTransactions, store.rollbackTransactions)
 	store.RegisterFunctions(false, blockchain.StoreFuncNames.RollbackUnspend, store.rollbackUnspend)
+	store.RegisterFunctions(false, rollbackBlockFilter, store.rollbackBlockFilter)
+	store.RegisterFunctions(false, rollbackOutputMemos, store.rollbackOutputMemos)
+	store.RegisterFunctions(false, rollbackAssetIndex, store.rollbackAssetIndex)
+	if addressIndex {
+		store.RegisterFunctions(true, persistAddressIndex, store.persistAddressIndex)
+		store.RegisterFunctions(false, rollbackAddressIndex, store.rollbackAddressIndex)
+	}
+
+	if err := store.indexAssets(); err != nil {
+		return nil, err
+	}
+	if err := store.indexBlockFilters(); err != nil {
+		return nil, err
+	}
+	if addressIndex {
+		err = store.indexAddresses()
+	} else {
+		err = store.dropAddressIndex()
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	return store, nil
 }
@@ -165,8 +232,8 @@ func (c *TokenChainStore) GetTxReference(tx *types.Transaction) (map[*types.Inpu
 	return reference, nil
 }
 
-func (c *TokenChainStore) GetUnspents(programHash Uint168) (map[Uint256][]*utxo, error) {
-	uxtoUnspents := make(map[Uint256][]*utxo)
+func (c *TokenChainStore) GetUnspents(programHash Uint168) (map[Uint256][]*UTXO, error) {
+	uxtoUnspents := make(map[Uint256][]*UTXO)
 
 	prefix := []byte{byte(IX_Unspent_UTXO)}
 	key := append(prefix, programHash.Bytes()...)
@@ -189,9 +256,9 @@ func (c *TokenChainStore) GetUnspents(programHash Uint168) (map[Uint256][]*utxo,
 		}
 
 		// read unspent list in store
-		unspents := make([]*utxo, listNum)
+		unspents := make([]*UTXO, listNum)
 		for i := 0; i < int(listNum); i++ {
-			var u utxo
+			var u UTXO
 			err := u.Deserialize(r)
 			if err != nil {
 				return nil, err
@@ -205,7 +272,79 @@ func (c *TokenChainStore) GetUnspents(programHash Uint168) (map[Uint256][]*utxo,
 	return uxtoUnspents, nil
 }
 
-func (c *TokenChainStore) GetUnspentElementFromProgramHash(programHash Uint168, assetid Uint256, height uint32) ([]*utxo, error) {
+// ListUnspents iterates the unspent outputs of the program hash in the order
+// of the unspent index, which is the order of asset id and the key height,
+// and the order of txid and index within a key.  Each output is passed with
+// its cursor in the index and the height of its transaction.  Only the
+// outputs of the asset are iterated if assetID is not nil.  The iteration
+// seeks to the cursor and starts after it if it is not empty, and stops if fn
+// returns false.
+func (c *TokenChainStore) ListUnspents(programHash Uint168, assetID *Uint256, cursor []byte,
+	fn func(cursor []byte, height uint32, u *UTXO) bool) error {
+	if len(cursor) > 0 && len(cursor) != UnspentCursorSize {
+		return errors.New("invalid unspent cursor")
+	}
+	prefix := []byte{byte(IX_Unspent_UTXO)}
+	prefix = append(prefix, programHash.Bytes()...)
+	if assetID != nil {
+		prefix = append(prefix, assetID.Bytes()...)
+	}
+	iter := c.NewIterator(prefix)
+	defer iter.Release()
+
+	var ok bool
+	if len(cursor) > 0 {
+		// Seek to the key of the cursor, outputs before the cursor in the
+		// key are skipped below.
+		key := append([]byte{byte(IX_Unspent_UTXO)}, cursor[:unspentKeySize]...)
+		ok = iter.Seek(key)
+	} else {
+		ok = iter.Next()
+	}
+	for ; ok; ok = iter.Next() {
+		key := iter.Key()
+		if len(key) != 1+unspentKeySize {
+			continue
+		}
+		height := binary.LittleEndian.Uint32(key[1+21+32:])
+
+		r := bytes.NewReader(iter.Value())
+		listNum, err := ReadVarUint(r, 0)
+		if err != nil {
+			return err
+		}
+		unspents := make([]*UTXO, listNum)
+		for i := range unspents {
+			var u UTXO
+			if err := u.Deserialize(r); err != nil {
+				return err
+			}
+			unspents[i] = &u
+		}
+		sort.Slice(unspents, func(i, j int) bool {
+			if cmp := bytes.Compare(unspents[i].TxID[:], unspents[j].TxID[:]); cmp != 0 {
+				return cmp < 0
+			}
+			return unspents[i].Index < unspents[j].Index
+		})
+
+		for _, u := range unspents {
+			next := make([]byte, UnspentCursorSize)
+			copy(next, key[1:])
+			copy(next[unspentKeySize:], u.TxID[:])
+			binary.BigEndian.PutUint32(next[unspentKeySize+32:], u.Index)
+			if len(cursor) > 0 && bytes.Compare(next, cursor) <= 0 {
+				continue
+			}
+			if !fn(next, height, u) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (c *TokenChainStore) GetUnspentElementFromProgramHash(programHash Uint168, assetid Uint256, height uint32) ([]*UTXO, error) {
 	prefix := []byte{byte(IX_Unspent_UTXO)}
 	prefix = append(prefix, programHash.Bytes()...)
 	prefix = append(prefix, assetid.Bytes()...)
@@ -225,9 +364,9 @@ func (c *TokenChainStore) GetUnspentElementFromProgramHash(programHash Uint168,
 	}
 
 	// read unspent list in store
-	unspents := make([]*utxo, listNum)
+	unspents := make([]*UTXO, listNum)
 	for i := 0; i < int(listNum); i++ {
-		var u utxo
+		var u UTXO
 		err := u.Deserialize(r)
 		if err != nil {
 			return nil, err
@@ -239,7 +378,7 @@ func (c *TokenChainStore) GetUnspentElementFromProgramHash(programHash Uint168,
 	return unspents, nil
 }
 
-func (c *TokenChainStore) PersistUnspentWithProgramHash(batch database.Batch, programHash Uint168, assetid Uint256, height uint32, unspents []*utxo) error {
+func (c *TokenChainStore) PersistUnspentWithProgramHash(batch database.Batch, programHash Uint168, assetid Uint256, height uint32, unspents []*UTXO) error {
 	prefix := []byte{byte(IX_Unspent_UTXO)}
 	prefix = append(prefix, programHash.Bytes()...)
 	prefix = append(prefix, assetid.Bytes()...)
@@ -267,7 +406,7 @@ func (c *TokenChainStore) PersistUnspentWithProgramHash(batch database.Batch, pr
 }
 
 func (c *TokenChainStore) persistUnspendUTXOs(batch database.Batch, b *types.Block) error {
-	unspendUTXOs := make(map[Uint168]map[Uint256]map[uint32][]*utxo)
+	unspendUTXOs := make(map[Uint168]map[Uint256]map[uint32][]*UTXO)
 	curHeight := b.Header.Height
 
 	for _, txn := range b.Transactions {
@@ -276,29 +415,29 @@ func (c *TokenChainStore) persistUnspendUTXOs(batch database.Batch, b *types.Blo
 			assetID := output.AssetID
 
 			if _, ok := unspendUTXOs[programHash]; !ok {
-				unspendUTXOs[programHash] = make(map[Uint256]map[uint32][]*utxo)
+				unspendUTXOs[programHash] = make(map[Uint256]map[uint32][]*UTXO)
 			}
 
 			if _, ok := unspendUTXOs[programHash][assetID]; !ok {
-				unspendUTXOs[programHash][assetID] = make(map[uint32][]*utxo, 0)
+				unspendUTXOs[programHash][assetID] = make(map[uint32][]*UTXO, 0)
 			}
 
 			if _, ok := unspendUTXOs[programHash][assetID][curHeight]; !ok {
 				var err error
 				unspendUTXOs[programHash][assetID][curHeight], err = c.GetUnspentElementFromProgramHash(programHash, assetID, curHeight)
 				if err != nil {
-					unspendUTXOs[programHash][assetID][curHeight] = make([]*utxo, 0)
+					unspendUTXOs[programHash][assetID][curHeight] = make([]*UTXO, 0)
 				}
 
 			}
 			var valueBytes []byte
-			var u utxo
+			var u UTXO
 			if assetID.IsEqual(types.GetSystemAssetId()) {
 				valueBytes, _ = output.Value.Bytes()
 			} else {
-				valueBytes = output.TokenValue.Bytes()
+				valueBytes = core.NewTokenAmount(&output.TokenValue).Bytes()
 			}
-			u = utxo{txn.Hash(), uint32(index), assetID, valueBytes}
+			u = UTXO{txn.Hash(), uint32(index), assetID, valueBytes}
 			unspendUTXOs[programHash][assetID][curHeight] = append(unspendUTXOs[programHash][assetID][curHeight], &u)
 		}
 
@@ -314,10 +453,10 @@ func (c *TokenChainStore) persistUnspendUTXOs(batch database.Batch, b *types.Blo
 				assetID := referTxnOutput.AssetID
 
 				if _, ok := unspendUTXOs[programHash]; !ok {
-					unspendUTXOs[programHash] = make(map[Uint256]map[uint32][]*utxo)
+					unspendUTXOs[programHash] = make(map[Uint256]map[uint32][]*UTXO)
 				}
 				if _, ok := unspendUTXOs[programHash][assetID]; !ok {
-					unspendUTXOs[programHash][assetID] = make(map[uint32][]*utxo)
+					unspendUTXOs[programHash][assetID] = make(map[uint32][]*UTXO)
 				}
 
 				if _, ok := unspendUTXOs[programHash][assetID][height]; !ok {
@@ -391,17 +530,17 @@ func (c *TokenChainStore) rollbackTransactions(batch database.Batch, b *types.Bl
 }
 
 func (c *TokenChainStore) rollbackUnspendUTXOs(batch database.Batch, b *types.Block) error {
-	unspendUTXOs := make(map[Uint168]map[Uint256]map[uint32][]*utxo)
+	unspendUTXOs := make(map[Uint168]map[Uint256]map[uint32][]*UTXO)
 	height := b.Header.Height
 	for _, txn := range b.Transactions {
 		for index, output := range txn.Outputs {
 			programHash := output.ProgramHash
 			assetID := output.AssetID
 			if _, ok := unspendUTXOs[programHash]; !ok {
-				unspendUTXOs[programHash] = make(map[Uint256]map[uint32][]*utxo)
+				unspendUTXOs[programHash] = make(map[Uint256]map[uint32][]*UTXO)
 			}
 			if _, ok := unspendUTXOs[programHash][assetID]; !ok {
-				unspendUTXOs[programHash][assetID] = make(map[uint32][]*utxo)
+				unspendUTXOs[programHash][assetID] = make(map[uint32][]*UTXO)
 			}
 			if _, ok := unspendUTXOs[programHash][assetID][height]; !ok {
 				var err error
@@ -411,13 +550,13 @@ func (c *TokenChainStore) rollbackUnspendUTXOs(batch database.Batch, b *types.Bl
 				}
 			}
 			var valueBytes []byte
-			var u utxo
+			var u UTXO
 			if assetID.IsEqual(types.GetSystemAssetId()) {
 				valueBytes, _ = output.Value.Bytes()
 			} else {
-				valueBytes = output.TokenValue.Bytes()
+				valueBytes = core.NewTokenAmount(&output.TokenValue).Bytes()
 			}
-			u = utxo{txn.Hash(), uint32(index), assetID, valueBytes}
+			u = UTXO{txn.Hash(), uint32(index), assetID, valueBytes}
 			var position int
 			for i, unspend := range unspendUTXOs[programHash][assetID][height] {
 				if unspend.TxID == u.TxID && unspend.Index == u.Index {
@@ -439,25 +578,25 @@ func (c *TokenChainStore) rollbackUnspendUTXOs(batch database.Batch, b *types.Bl
 				programHash := referTxnOutput.ProgramHash
 				assetID := referTxnOutput.AssetID
 				if _, ok := unspendUTXOs[programHash]; !ok {
-					unspendUTXOs[programHash] = make(map[Uint256]map[uint32][]*utxo)
+					unspendUTXOs[programHash] = make(map[Uint256]map[uint32][]*UTXO)
 				}
 				if _, ok := unspendUTXOs[programHash][assetID]; !ok {
-					unspendUTXOs[programHash][assetID] = make(map[uint32][]*utxo)
+					unspendUTXOs[programHash][assetID] = make(map[uint32][]*UTXO)
 				}
 				if _, ok := unspendUTXOs[programHash][assetID][hh]; !ok {
 					unspendUTXOs[programHash][assetID][hh], err = c.GetUnspentElementFromProgramHash(programHash, assetID, hh)
 					if err != nil {
-						unspendUTXOs[programHash][assetID][hh] = make([]*utxo, 0)
+						unspendUTXOs[programHash][assetID][hh] = make([]*UTXO, 0)
 					}
 				}
 				var valueBytes []byte
-				var u utxo
+				var u UTXO
 				if assetID.IsEqual(types.GetSystemAssetId()) {
 					valueBytes, _ = referTxnOutput.Value.Bytes()
 				} else {
-					valueBytes = referTxnOutput.TokenValue.Bytes()
+					valueBytes = core.NewTokenAmount(&referTxnOutput.TokenValue).Bytes()
 				}
-				u = utxo{txn.Hash(), uint32(index), assetID, valueBytes}
+				u = UTXO{txn.Hash(), uint32(index), assetID, valueBytes}
 				unspendUTXOs[programHash][assetID][hh] = append(unspendUTXOs[programHash][assetID][hh], &u)
 			}
 		}
@@ -0,0 +1,68 @@
+//go:build go1.18
+// +build go1.18
+
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzUTXO(f *testing.F) {
+	for _, u := range testUTXOs() {
+		buf := new(bytes.Buffer)
+		if err := u.Serialize(buf); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var u UTXO
+		if err := u.Deserialize(bytes.NewReader(data)); err != nil {
+			return
+		}
+		buf := new(bytes.Buffer)
+		if err := u.Serialize(buf); err != nil {
+			t.Fatalf("encode decoded utxo: %v", err)
+		}
+		var decoded UTXO
+		if err := decoded.Deserialize(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatalf("decode encoded utxo: %v", err)
+		}
+		if u.TxID != decoded.TxID || u.Index != decoded.Index ||
+			u.AssetID != decoded.AssetID || !bytes.Equal(u.Value, decoded.Value) {
+			t.Fatalf("utxo changed after round trip")
+		}
+	})
+}
+
+func FuzzAssetInfo(f *testing.F) {
+	for _, a := range testAssetInfos() {
+		buf := new(bytes.Buffer)
+		if err := a.Serialize(buf); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var a AssetInfo
+		if err := a.Deserialize(bytes.NewReader(data)); err != nil {
+			return
+		}
+		buf := new(bytes.Buffer)
+		if err := a.Serialize(buf); err != nil {
+			t.Fatalf("encode decoded asset info: %v", err)
+		}
+		var decoded AssetInfo
+		if err := decoded.Deserialize(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatalf("decode encoded asset info: %v", err)
+		}
+		if a.Name != decoded.Name || a.Description != decoded.Description ||
+			a.Precision != decoded.Precision || a.AssetType != decoded.AssetType ||
+			a.Height != decoded.Height {
+			t.Fatalf("asset info changed after round trip")
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package blockchain
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func testUTXOs() []*UTXO {
+	ela, _ := common.Fixed64(100000000).Bytes()
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), core.MaxTokenValueDataSize*8), big.NewInt(1))
+	return []*UTXO{
+		{TxID: common.Uint256{0x01}, Index: 0, AssetID: types.GetSystemAssetId(), Value: ela},
+		{TxID: common.Uint256{0x02}, Index: 1, AssetID: common.Uint256{0x03}, Value: []byte{}},
+		{TxID: common.Uint256{0x04}, Index: 65535, AssetID: common.Uint256{0x05}, Value: []byte{0x01}},
+		{TxID: common.Uint256{0x06}, Index: 7, AssetID: common.Uint256{0x07}, Value: max.Bytes()},
+	}
+}
+
+func testAssetInfos() []*AssetInfo {
+	return []*AssetInfo{
+		{Asset: types.Asset{Name: "ELA", Precision: 8}},
+		{Asset: types.Asset{Name: "TOKEN", Description: "test token", Precision: 18}, Height: 100},
+		{Asset: types.Asset{Name: "T", Precision: 0}, Height: 0xffffffff},
+	}
+}
+
+func TestUTXORoundTrip(t *testing.T) {
+	for _, u := range testUTXOs() {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, u.Serialize(buf))
+		data := buf.Bytes()
+
+		var decoded UTXO
+		assert.NoError(t, decoded.Deserialize(bytes.NewReader(data)))
+		assert.Equal(t, u.TxID, decoded.TxID)
+		assert.Equal(t, u.Index, decoded.Index)
+		assert.Equal(t, u.AssetID, decoded.AssetID)
+		assert.Equal(t, u.Value, decoded.Value)
+		assert.Equal(t, u.ValueString(core.TokenPrecision), decoded.ValueString(core.TokenPrecision))
+		assert.Equal(t, u.UnitsString(), decoded.UnitsString())
+
+		for i := 0; i < len(data); i++ {
+			assert.Error(t, new(UTXO).Deserialize(bytes.NewReader(data[:i])))
+		}
+	}
+
+	// Value exceeds MaxTokenValueDataSize.
+	u := UTXO{Value: make([]byte, core.MaxTokenValueDataSize+1)}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, u.Serialize(buf))
+	assert.Error(t, new(UTXO).Deserialize(buf))
+}
+
+func TestUTXOValueString(t *testing.T) {
+	ela, _ := common.Fixed64(150000000).Bytes()
+	u := UTXO{AssetID: types.GetSystemAssetId(), Value: ela}
+	assert.Equal(t, "150000000", u.UnitsString())
+
+	// Fractional token values.
+	half := new(big.Int).Mul(big.NewInt(5), new(big.Int).Exp(big.NewInt(10), big.NewInt(17), nil))
+	u = UTXO{AssetID: common.Uint256{0x01}, Value: half.Bytes()}
+	assert.Equal(t, "0.50000000", u.ValueString(8))
+	assert.Equal(t, "0.5", u.ValueString(0))
+	assert.Equal(t, "0.500000000000000000", u.ValueString(core.TokenPrecision))
+	assert.Equal(t, "500000000000000000", u.UnitsString())
+
+	u = UTXO{AssetID: common.Uint256{0x01}, Value: []byte{0x01}}
+	assert.Equal(t, "0.000000000000000001", u.ValueString(4))
+	assert.Equal(t, "1", u.UnitsString())
+
+	u = UTXO{AssetID: common.Uint256{0x01}, Value: []byte{}}
+	assert.Equal(t, "0", u.ValueString(0))
+	assert.Equal(t, "0.00", u.ValueString(2))
+	assert.Equal(t, "0", u.UnitsString())
+
+	// Very large token values.
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), core.MaxTokenValueDataSize*8), big.NewInt(1))
+	digits := max.String()
+	point := len(digits) - core.TokenPrecision
+	u = UTXO{AssetID: common.Uint256{0x01}, Value: max.Bytes()}
+	assert.Equal(t, digits[:point]+"."+digits[point:], u.ValueString(0))
+	assert.Equal(t, digits, u.UnitsString())
+
+	whole := new(big.Int).Mul(big.NewInt(123456789), new(big.Int).Exp(big.NewInt(10), big.NewInt(40), nil))
+	u = UTXO{AssetID: common.Uint256{0x01}, Value: whole.Bytes()}
+	assert.Equal(t, "1234567890000000000000000000000", u.ValueString(0))
+	assert.Equal(t, "1234567890000000000000000000000.000", u.ValueString(3))
+}
+
+func TestAssetInfoRoundTrip(t *testing.T) {
+	for _, a := range testAssetInfos() {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, a.Serialize(buf))
+		data := buf.Bytes()
+
+		var decoded AssetInfo
+		r := bytes.NewReader(data)
+		assert.NoError(t, decoded.Deserialize(r))
+		assert.Equal(t, 0, r.Len())
+		assert.Equal(t, a.Name, decoded.Name)
+		assert.Equal(t, a.Description, decoded.Description)
+		assert.Equal(t, a.Precision, decoded.Precision)
+		assert.Equal(t, a.Height, decoded.Height)
+
+		for i := 0; i < len(data); i++ {
+			assert.Error(t, new(AssetInfo).Deserialize(bytes.NewReader(data[:i])))
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package blockchain
+
+import (
+	"github.com/elastos/Elastos.ELA.SideChain/events"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+)
+
+// BlockListener is notified when blocks are connected to or disconnected from
+// the best chain, after the changes are committed into the store.  The
+// callbacks are invoked in the event goroutine of the chain, so they must
+// return quickly.
+type BlockListener interface {
+	OnBlockPersisted(b *types.Block)
+	OnBlockRolledBack(b *types.Block)
+}
+
+// AddListener adds the listener to be notified of block changes, it must be
+// called before the store starts persisting blocks.
+func (c *TokenChainStore) AddListener(listener BlockListener) {
+	if len(c.listeners) == 0 {
+		events.Subscribe(c.handleEvent)
+	}
+	c.listeners = append(c.listeners, listener)
+}
+
+// handleEvent notifies the listeners of the block events of the chain, which
+// are sent after the blocks are committed, so the listeners always see the
+// changes in the store.
+func (c *TokenChainStore) handleEvent(e *events.Event) {
+	switch e.Type {
+	case events.ETBlockConnected:
+		if b, ok := e.Data.(*types.Block); ok {
+			for _, listener := range c.listeners {
+				listener.OnBlockPersisted(b)
+			}
+		}
+
+	case events.ETBlockDisconnected:
+		if b, ok := e.Data.(*types.Block); ok {
+			for _, listener := range c.listeners {
+				listener.OnBlockRolledBack(b)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	"github.com/elastos/Elastos.ELA.SideChain/database"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	. "github.com/elastos/Elastos.ELA/common"
+)
+
+const (
+	IX_Output_Memo = 0x94
+
+	persistOutputMemos  = "persistOutputMemos"
+	rollbackOutputMemos = "rollbackOutputMemos"
+)
+
+// outputMemoPrefix returns the key prefix of outputs with the memo, memos are
+// indexed by their SHA256 hash.
+func outputMemoPrefix(memo []byte) []byte {
+	hash := sha256.Sum256(memo)
+	return append([]byte{IX_Output_Memo}, hash[:]...)
+}
+
+func outputMemoKey(memo []byte, txID Uint256, index uint16) []byte {
+	key := bytes.NewBuffer(outputMemoPrefix(memo))
+	txID.Serialize(key)
+	WriteUint16(key, index)
+	return key.Bytes()
+}
+
+// GetOutputsByMemo returns the outpoints of outputs with the given memo.
+func (c *TokenChainStore) GetOutputsByMemo(memo []byte) ([]types.OutPoint, error) {
+	var outpoints []types.OutPoint
+
+	prefix := outputMemoPrefix(memo)
+	iter := c.NewIterator(prefix)
+	defer iter.Release()
+	for iter.Next() {
+		r := bytes.NewReader(iter.Key()[len(prefix):])
+		var outpoint types.OutPoint
+		if err := outpoint.TxID.Deserialize(r); err != nil {
+			return nil, err
+		}
+		index, err := ReadUint16(r)
+		if err != nil {
+			return nil, err
+		}
+		outpoint.Index = index
+		outpoints = append(outpoints, outpoint)
+	}
+
+	return outpoints, nil
+}
+
+// persistOutputMemos indexes the memos of outputs, memo attributes of blocks
+// before output memos are activated are not validated and may be invalid, they
+// are not indexed.
+func (c *TokenChainStore) persistOutputMemos(batch database.Batch, b *types.Block) error {
+	if !c.assetParams.IsOutputMemoActive(b.Header.Height) {
+		return nil
+	}
+	for _, txn := range b.Transactions {
+		memos, err := core.OutputMemos(txn)
+		if err != nil {
+			continue
+		}
+		txID := txn.Hash()
+		for index, memo := range memos {
+			if err := batch.Put(outputMemoKey(memo, txID, uint16(index)), []byte{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *TokenChainStore) rollbackOutputMemos(batch database.Batch, b *types.Block) error {
+	if !c.assetParams.IsOutputMemoActive(b.Header.Height) {
+		return nil
+	}
+	for _, txn := range b.Transactions {
+		memos, err := core.OutputMemos(txn)
+		if err != nil {
+			continue
+		}
+		txID := txn.Hash()
+		for index, memo := range memos {
+			batch.Delete(outputMemoKey(memo, txID, uint16(index)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package blockchain
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputMemoActivation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outputmemo")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assetParams := params.MainNetAssetParams
+	assetParams.OutputMemoHeight = 2
+	store, err := NewChainStore(params.GenesisBlock, params.ElaAssetId, &assetParams, dir, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+
+	memo := []byte("invoice")
+	memoTx := func(lockTime uint32) *types.Transaction {
+		tx := &types.Transaction{
+			TxType:     types.TransferAsset,
+			Payload:    &types.PayloadTransferAsset{},
+			Attributes: []*types.Attribute{},
+			Inputs:     []*types.Input{},
+			Outputs: []*types.Output{{
+				AssetID:     common.Uint256{0x01},
+				ProgramHash: common.Uint168{0x21, 0x01},
+			}},
+			LockTime: lockTime,
+			Programs: []*types.Program{},
+		}
+		assert.NoError(t, core.SetOutputMemos(tx, map[int][]byte{0: memo}))
+		return tx
+	}
+
+	// Memos of blocks before the activation are not indexed.
+	var txs []*types.Transaction
+	prev := params.GenesisBlock.Hash()
+	for height := uint32(1); height <= 2; height++ {
+		header := params.GenesisBlock.Header
+		header.Height = height
+		header.Previous = prev
+		tx := memoTx(height)
+		block := &types.Block{Header: header, Transactions: []*types.Transaction{tx}}
+		assert.NoError(t, store.SaveBlock(block))
+		txs = append(txs, tx)
+		prev = block.Hash()
+	}
+
+	outpoints, err := store.GetOutputsByMemo(memo)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.OutPoint{{TxID: txs[1].Hash()}}, outpoints)
+}
@@ -0,0 +1,190 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+
+	"github.com/elastos/Elastos.ELA.SideChain/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	. "github.com/elastos/Elastos.ELA/common"
+)
+
+// AssetUTXOStats is the statistics of the unspent outputs of an asset.
+type AssetUTXOStats struct {
+	Count     int
+	Addresses int
+
+	// Total is the total value in base units, ELA values are counted in the
+	// smallest unit of Fixed64.
+	Total *big.Int
+}
+
+// UTXOSetStats is the statistics of the unspent outputs at the block.
+type UTXOSetStats struct {
+	Height    uint32
+	BlockHash Uint256
+	TxOuts    int
+	Assets    map[Uint256]*AssetUTXOStats
+
+	// Hash is the SHA-256 of the unspent outputs, which are hashed in the
+	// order of program hash, asset id, height, txid and index, so it is the
+	// same for the same set of unspent outputs.
+	Hash Uint256
+}
+
+// IsUnspent returns if the output of the transaction at the height is not
+// spent.
+func (c *TokenChainStore) IsUnspent(programHash Uint168, assetID Uint256, height uint32,
+	txID Uint256, index uint32) bool {
+	unspents, err := c.GetUnspentElementFromProgramHash(programHash, assetID, height)
+	if err != nil {
+		return false
+	}
+	for _, u := range unspents {
+		if u.TxID.IsEqual(txID) && u.Index == index {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUTXOSetStats returns the statistics of all the unspent outputs at the
+// best block.  The statistics are cached till the next block, so the result
+// is shared by the callers and must not be modified.
+func (c *TokenChainStore) GetUTXOSetStats() (*UTXOSetStats, error) {
+	c.statsMtx.Lock()
+	defer c.statsMtx.Unlock()
+
+	if c.utxoStats != nil && c.utxoStats.BlockHash.IsEqual(c.GetCurrentBlockHash()) {
+		return c.utxoStats, nil
+	}
+	stats, err := c.utxoSetStats()
+	if err != nil {
+		return nil, err
+	}
+	c.utxoStats = stats
+	return stats, nil
+}
+
+// utxoSetStats computes the statistics of the unspent outputs.  A single
+// iterator reads both the best block and the unspent outputs, since the
+// iterator reads a consistent snapshot of the database, blocks persisted
+// during the iteration are not counted.
+func (c *TokenChainStore) utxoSetStats() (*UTXOSetStats, error) {
+	stats := &UTXOSetStats{Assets: make(map[Uint256]*AssetUTXOStats)}
+
+	iter := c.NewIterator(nil)
+	defer iter.Release()
+
+	currentBlockKey := []byte{byte(blockchain.SYS_CurrentBlock)}
+	if !iter.Seek(currentBlockKey) || !bytes.Equal(iter.Key(), currentBlockKey) {
+		return nil, errors.New("current block not found")
+	}
+	r := bytes.NewReader(iter.Value())
+	if err := stats.BlockHash.Deserialize(r); err != nil {
+		return nil, err
+	}
+	height, err := ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	stats.Height = height
+
+	lastAddresses := make(map[Uint256]Uint168)
+	hash := sha256.New()
+
+	prefix := []byte{byte(IX_Unspent_UTXO)}
+	for ok := iter.Seek(prefix); ok && bytes.HasPrefix(iter.Key(), prefix); ok = iter.Next() {
+		rk := bytes.NewReader(iter.Key())
+
+		// skip prefix
+		if _, err := ReadBytes(rk, 1); err != nil {
+			return nil, err
+		}
+		var programHash Uint168
+		if err := programHash.Deserialize(rk); err != nil {
+			return nil, err
+		}
+		var assetID Uint256
+		if err := assetID.Deserialize(rk); err != nil {
+			return nil, err
+		}
+		height, err := ReadUint32(rk)
+		if err != nil {
+			return nil, err
+		}
+
+		r := bytes.NewReader(iter.Value())
+		listNum, err := ReadVarUint(r, 0)
+		if err != nil {
+			return nil, err
+		}
+		unspents := make([]*UTXO, listNum)
+		for i := range unspents {
+			var u UTXO
+			if err := u.Deserialize(r); err != nil {
+				return nil, err
+			}
+			unspents[i] = &u
+		}
+		if len(unspents) == 0 {
+			continue
+		}
+		sort.Slice(unspents, func(i, j int) bool {
+			if cmp := bytes.Compare(unspents[i].TxID[:], unspents[j].TxID[:]); cmp != 0 {
+				return cmp < 0
+			}
+			return unspents[i].Index < unspents[j].Index
+		})
+
+		asset, ok := stats.Assets[assetID]
+		if !ok {
+			asset = &AssetUTXOStats{Total: new(big.Int)}
+			stats.Assets[assetID] = asset
+		}
+		if last, ok := lastAddresses[assetID]; !ok || !last.IsEqual(programHash) {
+			asset.Addresses++
+			lastAddresses[assetID] = programHash
+		}
+		for _, u := range unspents {
+			value, err := utxoValue(assetID, u.Value)
+			if err != nil {
+				return nil, err
+			}
+			asset.Count++
+			asset.Total.Add(asset.Total, value)
+			stats.TxOuts++
+
+			programHash.Serialize(hash)
+			assetID.Serialize(hash)
+			WriteUint32(hash, height)
+			u.TxID.Serialize(hash)
+			WriteUint32(hash, u.Index)
+			WriteVarBytes(hash, value.Bytes())
+		}
+	}
+
+	copy(stats.Hash[:], hash.Sum(nil))
+	return stats, nil
+}
+
+// utxoValue returns the value of the unspent output in base units.
+func utxoValue(assetID Uint256, value []byte) (*big.Int, error) {
+	if assetID == types.GetSystemAssetId() {
+		number, err := Fixed64FromBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return big.NewInt(int64(*number)), nil
+	}
+	amount, err := core.TokenAmountFromBytes(value)
+	if err != nil {
+		return nil, err
+	}
+	return amount.Int(), nil
+}
@@ -0,0 +1,99 @@
+package blockchain
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// testUTXOStore returns a store which persists the transactions in a block
+// of height 1.
+func testUTXOStore(t *testing.T, txs []*types.Transaction) (*TokenChainStore, func()) {
+	dir, err := ioutil.TempDir("", "utxoset")
+	assert.NoError(t, err)
+	store, err := NewChainStore(params.GenesisBlock, params.ElaAssetId, &params.MainNetAssetParams, dir, false)
+	if !assert.NoError(t, err) {
+		os.RemoveAll(dir)
+		t.FailNow()
+	}
+
+	header := params.GenesisBlock.Header
+	header.Height = 1
+	header.Previous = params.GenesisBlock.Hash()
+	assert.NoError(t, store.SaveBlock(&types.Block{Header: header, Transactions: txs}))
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestUTXOSetStats(t *testing.T) {
+	payment := func(value common.Fixed64, programHashes ...common.Uint168) *types.Transaction {
+		tx := &types.Transaction{
+			TxType:     types.TransferAsset,
+			Payload:    &types.PayloadTransferAsset{},
+			Attributes: []*types.Attribute{},
+			Inputs:     []*types.Input{},
+			Programs:   []*types.Program{},
+		}
+		for _, programHash := range programHashes {
+			tx.Outputs = append(tx.Outputs, &types.Output{
+				AssetID:     params.ElaAssetId,
+				Value:       value,
+				ProgramHash: programHash,
+			})
+		}
+		return tx
+	}
+	a, b := common.Uint168{0x21, 0x01}, common.Uint168{0x21, 0x02}
+	tx1, tx2 := payment(100, a, b), payment(200, a)
+
+	// The outputs of the same key are listed in the order they are
+	// persisted, the hash does not depend on it.
+	store, cleanup := testUTXOStore(t, []*types.Transaction{tx1, tx2})
+	defer cleanup()
+	stats, err := store.GetUTXOSetStats()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), stats.Height)
+	assert.Equal(t, store.GetCurrentBlockHash(), stats.BlockHash)
+
+	reversed, cleanupReversed := testUTXOStore(t, []*types.Transaction{tx2, tx1})
+	defer cleanupReversed()
+	reversedStats, err := reversed.GetUTXOSetStats()
+	assert.NoError(t, err)
+	assert.Equal(t, stats.Hash, reversedStats.Hash)
+	assert.Equal(t, stats.TxOuts, reversedStats.TxOuts)
+	assert.NotEqual(t, stats.BlockHash, reversedStats.BlockHash)
+
+	elaStats := stats.Assets[params.ElaAssetId]
+	if assert.NotNil(t, elaStats) {
+		assert.Equal(t, 3, elaStats.Count)
+		assert.Equal(t, 2, elaStats.Addresses)
+		assert.Equal(t, int64(400), elaStats.Total.Int64())
+	}
+
+	// The statistics are cached till the next block.
+	cached, err := store.GetUTXOSetStats()
+	assert.NoError(t, err)
+	assert.True(t, stats == cached)
+
+	header := params.GenesisBlock.Header
+	header.Height = 2
+	header.Previous = store.GetCurrentBlockHash()
+	spend := payment(50, b)
+	spend.Inputs = []*types.Input{{Previous: types.OutPoint{TxID: tx2.Hash()}}}
+	assert.NoError(t, store.SaveBlock(&types.Block{Header: header, Transactions: []*types.Transaction{spend}}))
+
+	next, err := store.GetUTXOSetStats()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), next.Height)
+	assert.NotEqual(t, stats.Hash, next.Hash)
+	assert.Equal(t, 3, next.TxOuts)
+	assert.Equal(t, int64(250), next.Assets[params.ElaAssetId].Total.Int64())
+}
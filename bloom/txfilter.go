@@ -7,21 +7,41 @@ import (
 	"github.com/elastos/Elastos.ELA.SideChain/bloom"
 	"github.com/elastos/Elastos.ELA.SideChain/filter"
 	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
 	"github.com/elastos/Elastos.ELA/p2p/msg"
 )
 
+// Flags of the token bloom filter, they are serialized as an optional byte
+// after the filter load message, so filters loaded by old clients have no
+// flags set.
+const (
+	// FlagMatchRegistrations matches all register asset transactions.
+	FlagMatchRegistrations uint8 = 1 << iota
+)
+
 type txFilter struct {
 	filter *bloom.Filter
+	flags  uint8
 }
 
 func (f *txFilter) Load(filter []byte) error {
+	r := bytes.NewReader(filter)
 	var fl msg.FilterLoad
-	err := fl.Deserialize(bytes.NewReader(filter))
+	err := fl.Deserialize(r)
 	if err != nil {
 		return err
 	}
 
+	var flags uint8
+	if r.Len() > 0 {
+		flags, err = common.ReadUint8(r)
+		if err != nil {
+			return err
+		}
+	}
+
 	f.filter = bloom.LoadFilter(&fl)
+	f.flags = flags
 
 	return nil
 }
@@ -37,11 +57,41 @@ func (f *txFilter) Add(filter []byte) error {
 }
 
 func (f *txFilter) Match(tx *types.Transaction) bool {
-	// Match all register asset transactions.
-	if tx.IsRegisterAssetTx() {
+	if f.filter == nil || !f.filter.IsLoaded() {
+		return false
+	}
+
+	if tx.IsRegisterAssetTx() && f.matchRegistration(tx) {
 		return true
 	}
-	return f.filter.MatchTxAndUpdate(tx)
+
+	// Match the addresses first, so the outpoints of matched outputs are
+	// added into the filter.
+	matched := f.filter.MatchTxAndUpdate(tx)
+
+	txID := tx.Hash()
+	for i, output := range tx.Outputs {
+		if f.filter.Matches(output.AssetID.Bytes()) {
+			matched = true
+			f.filter.AddOutPoint(&types.OutPoint{TxID: txID, Index: uint16(i)})
+		}
+	}
+	return matched
+}
+
+// matchRegistration returns if the register asset transaction should be
+// matched by the flags, the registered asset ID or the asset controller.
+func (f *txFilter) matchRegistration(tx *types.Transaction) bool {
+	if f.flags&FlagMatchRegistrations != 0 {
+		return true
+	}
+	payload, ok := tx.Payload.(*types.PayloadRegisterAsset)
+	if !ok {
+		return false
+	}
+	assetID := payload.Asset.Hash()
+	return f.filter.Matches(assetID.Bytes()) ||
+		f.filter.Matches(payload.Controller.Bytes())
 }
 
 func NewTxFilter() filter.TxFilter {
@@ -0,0 +1,120 @@
+package bloom
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain/bloom"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	tokenAsset = common.Uint256{0x01, 0x02, 0x03}
+	otherAsset = common.Uint256{0x04, 0x05, 0x06}
+	controller = common.Uint168{0x21, 0x01, 0x02}
+	receiver   = common.Uint168{0x21, 0x03, 0x04}
+)
+
+func loadFilter(t *testing.T, flags uint8, elements ...[]byte) *txFilter {
+	bf := bloom.NewFilter(uint32(len(elements)+1), 0, 0.0001)
+	for _, e := range elements {
+		bf.Add(e)
+	}
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, bf.GetFilterLoadMsg().Serialize(buf))
+	if flags != 0 {
+		buf.WriteByte(flags)
+	}
+
+	f := NewTxFilter().(*txFilter)
+	assert.NoError(t, f.Load(buf.Bytes()))
+	return f
+}
+
+func registerAssetTx(name string, controller common.Uint168) *types.Transaction {
+	return &types.Transaction{
+		TxType: types.RegisterAsset,
+		Payload: &types.PayloadRegisterAsset{
+			Asset: types.Asset{
+				Name:      name,
+				Precision: 18,
+			},
+			Controller: controller,
+		},
+		Attributes: []*types.Attribute{},
+		Inputs:     []*types.Input{},
+		Outputs:    []*types.Output{},
+		Programs:   []*types.Program{},
+	}
+}
+
+func transferTx(inputs []*types.Input, assetID common.Uint256, to common.Uint168) *types.Transaction {
+	return &types.Transaction{
+		TxType:     types.TransferAsset,
+		Payload:    &types.PayloadTransferAsset{},
+		Attributes: []*types.Attribute{},
+		Inputs:     inputs,
+		Outputs: []*types.Output{{
+			AssetID:     assetID,
+			ProgramHash: to,
+			TokenValue:  *big.NewInt(1),
+		}},
+		Programs: []*types.Program{},
+	}
+}
+
+func TestTxFilter_MatchAssetID(t *testing.T) {
+	f := loadFilter(t, 0, tokenAsset.Bytes())
+
+	tx := transferTx(nil, tokenAsset, receiver)
+	assert.True(t, f.Match(tx))
+	assert.False(t, f.Match(transferTx(nil, otherAsset, receiver)))
+
+	// Spending the matched output is matched even it transfers other assets.
+	spend := transferTx([]*types.Input{{
+		Previous: types.OutPoint{TxID: tx.Hash(), Index: 0},
+	}}, otherAsset, receiver)
+	assert.True(t, f.Match(spend))
+}
+
+func TestTxFilter_MatchAddress(t *testing.T) {
+	f := loadFilter(t, 0, receiver.Bytes())
+
+	assert.True(t, f.Match(transferTx(nil, otherAsset, receiver)))
+	assert.False(t, f.Match(transferTx(nil, otherAsset, controller)))
+}
+
+func TestTxFilter_MatchRegistration(t *testing.T) {
+	registration := registerAssetTx("TOKEN", controller)
+	assetID := registration.Payload.(*types.PayloadRegisterAsset).Asset.Hash()
+
+	// Registrations are not matched by default.
+	f := loadFilter(t, 0, otherAsset.Bytes())
+	assert.False(t, f.Match(registration))
+
+	// Registrations are matched with the flag set.
+	f = loadFilter(t, FlagMatchRegistrations, otherAsset.Bytes())
+	assert.True(t, f.Match(registration))
+
+	// Registration of a loaded asset ID is matched.
+	f = loadFilter(t, 0, assetID.Bytes())
+	assert.True(t, f.Match(registration))
+	// The hash of the registration is not the asset ID.
+	f = loadFilter(t, 0, registration.Hash().Bytes())
+	assert.False(t, f.Match(registration))
+
+	// Registration of a loaded controller is matched.
+	f = loadFilter(t, 0, controller.Bytes())
+	assert.True(t, f.Match(registration))
+	assert.False(t, f.Match(registerAssetTx("OTHER", receiver)))
+}
+
+func TestTxFilter_NotLoaded(t *testing.T) {
+	f := NewTxFilter()
+	assert.Error(t, f.Add(tokenAsset.Bytes()))
+	assert.False(t, f.Match(registerAssetTx("TOKEN", controller)))
+}
@@ -0,0 +1,171 @@
+// tokentx decodes and creates raw token transactions offline, with the same
+// output codec as the token node.
+//
+// Usage:
+//
+//	tokentx decode [-precision assetid:precision]... <hex>
+//	tokentx create [-precision assetid:precision]... [-locktime height] <file>
+//
+// The create subcommand reads a JSON object with "inputs" and "outputs" in the
+// same format as the createrawtransaction RPC, from the file or stdin if the
+// file is "-".
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	sv "github.com/elastos/Elastos.ELA.SideChain.Token/service"
+
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// precisions is the asset precisions given by command line flags, since there
+// is no blockchain to look up offline.
+type precisions map[common.Uint256]byte
+
+func (p precisions) String() string {
+	var s []string
+	for assetID, precision := range p {
+		s = append(s, fmt.Sprintf("%s:%d", service.ToReversedString(assetID), precision))
+	}
+	return strings.Join(s, ",")
+}
+
+func (p precisions) Set(value string) error {
+	i := strings.LastIndexByte(value, ':')
+	if i < 0 {
+		return fmt.Errorf("invalid precision %q, want assetid:precision", value)
+	}
+	assetID, err := sv.ParseHash(value[:i])
+	if err != nil {
+		return fmt.Errorf("invalid asset id %s", value[:i])
+	}
+	precision, err := strconv.ParseUint(value[i+1:], 10, 8)
+	if err != nil || precision > core.TokenPrecision {
+		return fmt.Errorf("invalid precision %s", value[i+1:])
+	}
+	p[assetID] = byte(precision)
+	return nil
+}
+
+func (p precisions) lookup(assetID common.Uint256) (byte, bool) {
+	precision, ok := p[assetID]
+	return precision, ok
+}
+
+type createRequest struct {
+	Inputs  []sv.RawTxInput  `json:"inputs"`
+	Outputs []sv.RawTxOutput `json:"outputs"`
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:")
+	fmt.Fprintln(os.Stderr, "  tokentx decode [-precision assetid:precision]... <hex>")
+	fmt.Fprintln(os.Stderr, "  tokentx create [-precision assetid:precision]... [-locktime height] <file>")
+	os.Exit(2)
+}
+
+func decode(args []string) error {
+	p := make(precisions)
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	fs.Var(p, "precision", "precision of a token asset, in assetid:precision")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	data, err := common.HexStringToBytes(strings.TrimSpace(fs.Arg(0)))
+	if err != nil {
+		return fmt.Errorf("invalid hex string, %s", err)
+	}
+	var tx types.Transaction
+	if err := tx.Deserialize(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("invalid transaction, %s", err)
+	}
+
+	cfg := service.Config{
+		GetTransactionInfo: sv.GetTransactionInfo,
+		GetPayloadInfo:     sv.GetPayloadInfo,
+	}
+	// There is no height offline, so memo attributes are always decoded as
+	// output memos.
+	return printJSON(sv.DecodeTransaction(&cfg, &tx, p.lookup, true))
+}
+
+func create(args []string) error {
+	p := make(precisions)
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	fs.Var(p, "precision", "precision of a token asset, in assetid:precision")
+	lockTime := fs.Uint("locktime", 0, "lock time of the transaction")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	var data []byte
+	var err error
+	if fs.Arg(0) == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	var req createRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("invalid request, %s", err)
+	}
+
+	tx, err := sv.CreateRawTransaction(req.Inputs, req.Outputs, uint32(*lockTime), p.lookup)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := tx.Serialize(buf); err != nil {
+		return err
+	}
+	fmt.Println(common.BytesToHexString(buf.Bytes()))
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func main() {
+	core.Init()
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "decode":
+		err = decode(os.Args[2:])
+	case "create":
+		err = create(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
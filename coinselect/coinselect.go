@@ -0,0 +1,398 @@
+// Package coinselect selects unspent outputs to fund multi-asset transfer
+// transactions.  Token inputs are selected for each asset and ELA inputs for
+// the ELA outputs and the fee, and the changes satisfy the asset precision,
+// the dust policy and the exact token balance the validator requires.
+package coinselect
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	mp "github.com/elastos/Elastos.ELA.SideChain.Token/mempool"
+
+	"github.com/elastos/Elastos.ELA.SideChain/config"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+const (
+	// elaPrecision is the number of decimal places of ELA values.
+	elaPrecision = 8
+
+	// elaOutputSize is the serialized size of an ELA output.
+	elaOutputSize = 32 + 8 + 4 + 21
+
+	// standardProgramSize is the serialized size of the program of a
+	// standard signature, which is the 65 bytes parameter and the 35 bytes
+	// code with their lengths.
+	standardProgramSize = 1 + 65 + 1 + 35
+
+	// maxFeeIterations is the maximum number of times the fee is raised to
+	// cover the size of the transaction.
+	maxFeeIterations = 10
+)
+
+var (
+	// ErrInsufficientFunds is returned if the coins can not cover the target.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrDustChange is returned if the coins cover the target, but every
+	// selection leaves a change less than the dust threshold.
+	ErrDustChange = errors.New("change is less than the dust threshold")
+)
+
+// Coin is an unspent output that can be selected as an input.
+type Coin struct {
+	OutPoint    types.OutPoint
+	ProgramHash common.Uint168
+	AssetID     common.Uint256
+
+	// Value is the value in base units, ELA values are counted in the
+	// smallest unit of Fixed64.
+	Value *big.Int
+
+	// Height is the height of the block including the output.
+	Height uint32
+
+	// OutputLock is the height the output is locked till.
+	OutputLock uint32
+
+	// Locked is true if the output can not be spent in the next block, for
+	// the output lock or the coinbase maturity.
+	Locked bool
+}
+
+// Input returns the input spending the coin, the sequence of an input
+// spending a locked output must be math.MaxUint32-1.
+func (c *Coin) Input() *types.Input {
+	input := &types.Input{Previous: c.OutPoint}
+	if c.OutputLock > 0 {
+		input.Sequence = math.MaxUint32 - 1
+	}
+	return input
+}
+
+// Config is the configuration of the selector.
+type Config struct {
+	Store       *blockchain.TokenChainStore
+	ChainParams *config.Params
+
+	// Policy is the relay policy the changes must satisfy, it can be nil.
+	Policy *mp.Policy
+
+	// Strategy is the default strategy, largest first is used if it is nil.
+	Strategy Strategy
+}
+
+// Selector lists the coins of addresses and funds transactions with them.
+type Selector struct {
+	cfg Config
+}
+
+// New returns a selector of the configuration.
+func New(cfg *Config) *Selector {
+	s := &Selector{cfg: *cfg}
+	if s.cfg.Strategy == nil {
+		s.cfg.Strategy = LargestFirst{}
+	}
+	return s
+}
+
+// value returns the value of the output in base units.
+func (s *Selector) value(output *types.Output) *big.Int {
+	if output.AssetID.IsEqual(s.cfg.ChainParams.ElaAssetId) {
+		return big.NewInt(int64(output.Value))
+	}
+	return new(big.Int).Set(&output.TokenValue)
+}
+
+// Coins returns the unspent outputs of the address, locked ones included.
+func (s *Selector) Coins(programHash common.Uint168) ([]*Coin, error) {
+	unspents, err := s.cfg.Store.GetUnspents(programHash)
+	if err != nil {
+		return nil, err
+	}
+
+	bestHeight := s.cfg.Store.GetHeight()
+	var coins []*Coin
+	for assetID, utxos := range unspents {
+		for _, u := range utxos {
+			tx, height, err := s.cfg.Store.GetTransaction(u.TxID)
+			if err != nil || int(u.Index) >= len(tx.Outputs) {
+				return nil, errors.New("unknown transaction " + u.TxID.String() + " from persisted utxo")
+			}
+			output := tx.Outputs[u.Index]
+			locked := output.OutputLock > bestHeight
+			if tx.IsCoinBaseTx() && bestHeight-tx.LockTime < s.cfg.ChainParams.CoinbaseMaturity {
+				locked = true
+			}
+			coins = append(coins, &Coin{
+				OutPoint:    types.OutPoint{TxID: u.TxID, Index: uint16(u.Index)},
+				ProgramHash: programHash,
+				AssetID:     assetID,
+				Value:       s.value(output),
+				Height:      height,
+				OutputLock:  output.OutputLock,
+				Locked:      locked,
+			})
+		}
+	}
+	sort.Slice(coins, func(i, j int) bool { return lessOutPoint(coins[i], coins[j]) })
+	return coins, nil
+}
+
+// Request is a request to fund the outputs.
+type Request struct {
+	Outputs []*types.Output
+
+	// Memos is the memos of the outputs keyed by the output index.
+	Memos map[int][]byte
+
+	// Change is the address the changes are paid to.
+	Change common.Uint168
+
+	// MinFee is the minimum ELA fee, the minimum transaction fee of the
+	// chain is used if it is less.
+	MinFee common.Fixed64
+
+	// FeeRate is the minimum ELA fee per KB of the signed transaction.
+	FeeRate common.Fixed64
+
+	// Strategy overrides the default strategy of the selector if not nil.
+	Strategy Strategy
+}
+
+// Result is the inputs and changes funding the outputs.
+type Result struct {
+	Inputs  []*Coin
+	Outputs []*types.Output
+	Changes []*types.Output
+	Memos   map[int][]byte
+
+	// Fee is the ELA fee, which may exceed the required fee by a dropped
+	// change.
+	Fee common.Fixed64
+
+	// LockTime is the lock time the transaction needs to spend the locked
+	// outputs of the inputs.
+	LockTime uint32
+}
+
+// Transaction returns the unsigned transfer transaction of the result.
+func (r *Result) Transaction() (*types.Transaction, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	tx := &types.Transaction{
+		TxType:     types.TransferAsset,
+		Payload:    &types.PayloadTransferAsset{},
+		Attributes: []*types.Attribute{{Usage: types.Nonce, Data: nonce}},
+		Outputs:    append(append([]*types.Output{}, r.Outputs...), r.Changes...),
+		LockTime:   r.LockTime,
+		Programs:   []*types.Program{},
+	}
+	for _, c := range r.Inputs {
+		tx.Inputs = append(tx.Inputs, c.Input())
+	}
+	if err := core.SetOutputMemos(tx, r.Memos); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// EstimateSize returns the size of the transaction signed by the standard
+// programs of the input addresses.
+func EstimateSize(tx *types.Transaction, inputs []*Coin) int {
+	signers := make(map[common.Uint168]struct{})
+	for _, c := range inputs {
+		signers[c.ProgramHash] = struct{}{}
+	}
+	return tx.GetSize() + len(signers)*standardProgramSize
+}
+
+// precise returns if the value satisfies the precision of the asset.
+func (s *Selector) precise(assetID common.Uint256, precision byte, value *big.Int) bool {
+	if !assetID.IsEqual(s.cfg.ChainParams.ElaAssetId) {
+		return core.NewTokenAmount(value).IsPrecise(precision)
+	}
+	if precision >= elaPrecision {
+		return true
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(elaPrecision-precision)), nil)
+	return new(big.Int).Mod(value, unit).Sign() == 0
+}
+
+// Fund selects the coins funding the outputs.  Locked coins are never
+// selected, and the ELA fee covers both the minimum fee and the fee rate.
+func (s *Selector) Fund(coins []*Coin, req *Request) (*Result, error) {
+	if len(req.Outputs) == 0 {
+		return nil, errors.New("outputs can not be empty")
+	}
+	strategy := req.Strategy
+	if strategy == nil {
+		strategy = s.cfg.Strategy
+	}
+	elaAssetID := s.cfg.ChainParams.ElaAssetId
+
+	targets := make(map[common.Uint256]*big.Int)
+	counts := make(map[common.Uint256]int)
+	assets := make(map[common.Uint256]*blockchain.AssetInfo)
+	for _, output := range req.Outputs {
+		asset, ok := assets[output.AssetID]
+		if !ok {
+			var err error
+			asset, err = s.cfg.Store.GetAsset(output.AssetID)
+			if err != nil {
+				return nil, fmt.Errorf("unknown asset %s", output.AssetID.String())
+			}
+			assets[output.AssetID] = asset
+			targets[output.AssetID] = new(big.Int)
+		}
+		value := s.value(output)
+		if value.Sign() <= 0 {
+			return nil, errors.New("output value must be positive")
+		}
+		if !s.precise(output.AssetID, asset.Precision, value) {
+			return nil, fmt.Errorf("output value of asset %s exceeds the precision %d",
+				asset.Name, asset.Precision)
+		}
+		targets[output.AssetID].Add(targets[output.AssetID], value)
+		counts[output.AssetID]++
+	}
+
+	candidates := make(map[common.Uint256][]*Coin)
+	for _, c := range coins {
+		if !c.Locked {
+			candidates[c.AssetID] = append(candidates[c.AssetID], c)
+		}
+	}
+
+	// Select token inputs in the order of asset id, so the result is
+	// deterministic for deterministic strategies.
+	assetIDs := make([]common.Uint256, 0, len(targets))
+	for assetID := range targets {
+		if !assetID.IsEqual(elaAssetID) {
+			assetIDs = append(assetIDs, assetID)
+		}
+	}
+	sort.Slice(assetIDs, func(i, j int) bool {
+		return bytes.Compare(assetIDs[i][:], assetIDs[j][:]) < 0
+	})
+
+	// The addresses of the selected inputs are linked by the transaction, the
+	// later selections may prefer them.
+	linked := make(map[common.Uint168]struct{})
+	result := &Result{Outputs: req.Outputs, Memos: req.Memos}
+	for _, assetID := range assetIDs {
+		asset := assets[assetID]
+		target := &Target{Value: targets[assetID], MinChange: new(big.Int), MaxDrop: new(big.Int), Linked: linked}
+		if s.cfg.Policy != nil {
+			target.MinChange = s.cfg.Policy.DustThreshold(asset.Precision).Int()
+		}
+		selected, err := strategy.Select(candidates[assetID], target)
+		if err != nil {
+			return nil, fmt.Errorf("asset %s: %s", asset.Name, err)
+		}
+		result.Inputs = append(result.Inputs, selected...)
+		for _, c := range selected {
+			linked[c.ProgramHash] = struct{}{}
+		}
+
+		change, _ := target.Change(total(selected))
+		if change.Sign() == 0 {
+			continue
+		}
+		if !s.precise(assetID, asset.Precision, change) {
+			return nil, fmt.Errorf("asset %s: change exceeds the precision %d", asset.Name, asset.Precision)
+		}
+		if s.cfg.Policy != nil && s.cfg.Policy.MaxTokenOutputsPerAsset > 0 &&
+			counts[assetID]+1 > s.cfg.Policy.MaxTokenOutputsPerAsset {
+			return nil, fmt.Errorf("asset %s: change exceeds the limit of %d outputs",
+				asset.Name, s.cfg.Policy.MaxTokenOutputsPerAsset)
+		}
+		result.Changes = append(result.Changes, &types.Output{
+			AssetID:     assetID,
+			TokenValue:  *change,
+			ProgramHash: req.Change,
+		})
+	}
+
+	return s.fundFee(candidates[elaAssetID], targets[elaAssetID], linked, result, req, strategy)
+}
+
+// fundFee selects the ELA inputs covering the ELA outputs and the fee.  The
+// fee is raised until it covers the fee rate of the estimated size.
+func (s *Selector) fundFee(candidates []*Coin, value *big.Int, linked map[common.Uint168]struct{},
+	result *Result, req *Request, strategy Strategy) (*Result, error) {
+	elaAssetID := s.cfg.ChainParams.ElaAssetId
+	if value == nil {
+		value = new(big.Int)
+	}
+	minFee := common.Fixed64(s.cfg.ChainParams.MinTransactionFee)
+	if req.MinFee > minFee {
+		minFee = req.MinFee
+	}
+	requiredFee := func(size int) common.Fixed64 {
+		fee := common.Fixed64(math.Ceil(float64(req.FeeRate) * float64(size) / 1000))
+		if fee < minFee {
+			fee = minFee
+		}
+		return fee
+	}
+	// A change worth less than the fee it costs is dropped.
+	maxDrop := big.NewInt(int64(math.Ceil(float64(req.FeeRate) * elaOutputSize / 1000)))
+
+	tokenInputs, tokenChanges := result.Inputs, result.Changes
+	fee := minFee
+	for i := 0; i < maxFeeIterations; i++ {
+		target := &Target{
+			Value:   new(big.Int).Add(value, big.NewInt(int64(fee))),
+			MaxDrop: maxDrop,
+			Linked:  linked,
+		}
+		selected, err := strategy.Select(candidates, target)
+		if err != nil {
+			return nil, fmt.Errorf("ELA: %s", err)
+		}
+		selectedTotal := total(selected)
+		change, _ := target.Change(selectedTotal)
+
+		result.Inputs = append(append([]*Coin{}, tokenInputs...), selected...)
+		result.Changes = tokenChanges
+		if change.Sign() > 0 {
+			result.Changes = append(append([]*types.Output{}, tokenChanges...), &types.Output{
+				AssetID:     elaAssetID,
+				Value:       common.Fixed64(change.Int64()),
+				ProgramHash: req.Change,
+			})
+		}
+		paid := new(big.Int).Sub(selectedTotal, value)
+		result.Fee = common.Fixed64(paid.Sub(paid, change).Int64())
+		result.LockTime = 0
+		for _, c := range result.Inputs {
+			if c.OutputLock > result.LockTime {
+				result.LockTime = c.OutputLock
+			}
+		}
+
+		tx, err := result.Transaction()
+		if err != nil {
+			return nil, err
+		}
+		required := requiredFee(EstimateSize(tx, result.Inputs))
+		if result.Fee >= required {
+			return result, nil
+		}
+		fee = required
+	}
+	return nil, errors.New("can not find a fee covering the transaction size")
+}
@@ -0,0 +1,303 @@
+package coinselect
+
+import (
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	mp "github.com/elastos/Elastos.ELA.SideChain.Token/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testAddress   = common.Uint168{0x21, 0x01}
+	testRecipient = common.Uint168{0x21, 0x02}
+	testMiner     = common.Uint168{0x21, 0x03}
+	testPrecision = byte(4)
+)
+
+// testStore returns a store with a token of precision 4 registered and a
+// coinbase paying testMiner at height 1.
+func testStore(t *testing.T) (*blockchain.TokenChainStore, common.Uint256, func()) {
+	dir, err := ioutil.TempDir("", "coinselect")
+	assert.NoError(t, err)
+	store, err := blockchain.NewChainStore(params.GenesisBlock, params.ElaAssetId, &params.MainNetAssetParams, dir, false)
+	if !assert.NoError(t, err) {
+		os.RemoveAll(dir)
+		t.FailNow()
+	}
+
+	coinbase := &types.Transaction{
+		TxType:     types.CoinBase,
+		Payload:    &types.PayloadCoinBase{},
+		Attributes: []*types.Attribute{},
+		Inputs:     []*types.Input{},
+		Outputs: []*types.Output{{
+			AssetID:     params.ElaAssetId,
+			Value:       100000000,
+			ProgramHash: testMiner,
+		}},
+		LockTime: 1,
+		Programs: []*types.Program{},
+	}
+	register := &types.Transaction{
+		TxType: types.RegisterAsset,
+		Payload: &types.PayloadRegisterAsset{
+			Asset:      types.Asset{Name: "TOKEN", Precision: testPrecision},
+			Controller: testAddress,
+		},
+		Attributes: []*types.Attribute{},
+		Inputs:     []*types.Input{},
+		Outputs:    []*types.Output{},
+		Programs:   []*types.Program{},
+	}
+	header := params.GenesisBlock.Header
+	header.Height = 1
+	header.Previous = params.GenesisBlock.Hash()
+	assert.NoError(t, store.SaveBlock(&types.Block{
+		Header:       header,
+		Transactions: []*types.Transaction{coinbase, register},
+	}))
+
+	assetID := register.Payload.(*types.PayloadRegisterAsset).Asset.Hash()
+	return store, assetID, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func tokens(amount string) *big.Int {
+	value, err := core.ParseTokenAmount(amount, core.TokenPrecision)
+	if err != nil {
+		panic(err)
+	}
+	return value.Int()
+}
+
+func testCoin(assetID common.Uint256, index uint16, value *big.Int) *Coin {
+	return &Coin{
+		OutPoint:    types.OutPoint{TxID: common.Uint256{0x01}, Index: index},
+		ProgramHash: testAddress,
+		AssetID:     assetID,
+		Value:       value,
+		Height:      1,
+	}
+}
+
+func tokenOutput(assetID common.Uint256, value *big.Int) *types.Output {
+	return &types.Output{AssetID: assetID, TokenValue: *value, ProgramHash: testRecipient}
+}
+
+func elaOutput(value common.Fixed64) *types.Output {
+	return &types.Output{AssetID: params.ElaAssetId, Value: value, ProgramHash: testRecipient}
+}
+
+func TestFundTokenBalance(t *testing.T) {
+	store, assetID, cleanup := testStore(t)
+	defer cleanup()
+	s := New(&Config{Store: store, ChainParams: &params.MainNetParams, Policy: &mp.Policy{TokenDustUnits: 1}})
+
+	coins := []*Coin{
+		testCoin(assetID, 0, tokens("60")),
+		testCoin(assetID, 1, tokens("50.5")),
+		testCoin(params.ElaAssetId, 2, big.NewInt(100000000)),
+	}
+	result, err := s.Fund(coins, &Request{
+		Outputs: []*types.Output{tokenOutput(assetID, tokens("70.25"))},
+		Change:  testAddress,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The token inputs exactly balance the outputs and the change, which
+	// keeps the precision of the asset.
+	in, out := new(big.Int), new(big.Int)
+	var elaIn, elaOut common.Fixed64
+	for _, c := range result.Inputs {
+		if c.AssetID.IsEqual(assetID) {
+			in.Add(in, c.Value)
+		} else {
+			elaIn += common.Fixed64(c.Value.Int64())
+		}
+	}
+	for _, output := range append(append([]*types.Output{}, result.Outputs...), result.Changes...) {
+		if output.AssetID.IsEqual(assetID) {
+			out.Add(out, &output.TokenValue)
+			assert.True(t, core.NewTokenAmount(&output.TokenValue).IsPrecise(testPrecision))
+		} else {
+			elaOut += output.Value
+		}
+	}
+	assert.Equal(t, in, out)
+	assert.Equal(t, tokens("110.5"), in)
+	assert.Equal(t, result.Fee, elaIn-elaOut)
+	assert.True(t, int64(result.Fee) >= params.MainNetParams.MinTransactionFee)
+
+	// A change out of the precision of the asset is rejected.
+	imprecise := new(big.Int).Add(tokens("60"), big.NewInt(1))
+	_, err = s.Fund([]*Coin{testCoin(assetID, 0, imprecise), coins[2]}, &Request{
+		Outputs: []*types.Output{tokenOutput(assetID, tokens("10"))},
+		Change:  testAddress,
+	})
+	assert.Error(t, err)
+
+	// Outputs out of the precision are rejected.
+	_, err = s.Fund(coins, &Request{
+		Outputs: []*types.Output{tokenOutput(assetID, tokens("0.00001"))},
+		Change:  testAddress,
+	})
+	assert.Error(t, err)
+}
+
+func TestFundFeeRate(t *testing.T) {
+	store, _, cleanup := testStore(t)
+	defer cleanup()
+	s := New(&Config{Store: store, ChainParams: &params.MainNetParams})
+
+	var coins []*Coin
+	for i := 0; i < 20; i++ {
+		coins = append(coins, testCoin(params.ElaAssetId, uint16(i), big.NewInt(100000)))
+	}
+
+	// The first selection pays the minimum fee, which is raised until it
+	// covers the fee rate of the size of the transaction.
+	req := &Request{
+		Outputs: []*types.Output{elaOutput(100000)},
+		Change:  testAddress,
+		FeeRate: 100000,
+	}
+	result, err := s.Fund(coins, req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	tx, err := result.Transaction()
+	assert.NoError(t, err)
+	required := common.Fixed64(math.Ceil(float64(req.FeeRate) * float64(EstimateSize(tx, result.Inputs)) / 1000))
+	assert.True(t, result.Fee >= required)
+	assert.True(t, int64(required) > params.MainNetParams.MinTransactionFee)
+
+	// MinFee is applied if it is more than the fee rate requires.
+	req.FeeRate, req.MinFee = 0, 50000
+	result, err = s.Fund(coins, req)
+	assert.NoError(t, err)
+	assert.True(t, result.Fee >= 50000)
+
+	req.MinFee = 10000000
+	_, err = s.Fund(coins, req)
+	assert.Error(t, err)
+}
+
+func TestFundLockedCoins(t *testing.T) {
+	store, _, cleanup := testStore(t)
+	defer cleanup()
+	s := New(&Config{Store: store, ChainParams: &params.MainNetParams})
+
+	// The coinbase output is locked until it matures.
+	coins, err := s.Coins(testMiner)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(coins)) {
+		assert.True(t, coins[0].Locked)
+	}
+	_, err = s.Fund(coins, &Request{Outputs: []*types.Output{elaOutput(1000)}, Change: testMiner})
+	assert.Error(t, err)
+
+	// Spending an output locked till a passed height sets the sequence of
+	// the input and the lock time of the transaction.
+	unlocked := testCoin(params.ElaAssetId, 0, big.NewInt(100000000))
+	unlocked.OutputLock = 1
+	plain := testCoin(params.ElaAssetId, 1, big.NewInt(1000000))
+	locked := testCoin(params.ElaAssetId, 2, big.NewInt(1000000000))
+	locked.OutputLock, locked.Locked = 100, true
+	result, err := s.Fund([]*Coin{unlocked, plain, locked}, &Request{
+		Outputs: []*types.Output{elaOutput(10000000)},
+		Change:  testAddress,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []*Coin{unlocked}, result.Inputs)
+	tx, err := result.Transaction()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), tx.LockTime)
+	assert.Equal(t, uint32(math.MaxUint32-1), tx.Inputs[0].Sequence)
+
+	result, err = s.Fund([]*Coin{plain}, &Request{Outputs: []*types.Output{elaOutput(10000)}, Change: testAddress})
+	assert.NoError(t, err)
+	tx, err = result.Transaction()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), tx.LockTime)
+	assert.Equal(t, uint32(0), tx.Inputs[0].Sequence)
+}
+
+func TestFundMaxTokenOutputs(t *testing.T) {
+	store, assetID, cleanup := testStore(t)
+	defer cleanup()
+	s := New(&Config{
+		Store:       store,
+		ChainParams: &params.MainNetParams,
+		Policy:      &mp.Policy{MaxTokenOutputsPerAsset: 2},
+	})
+
+	coins := []*Coin{
+		testCoin(assetID, 0, tokens("10")),
+		testCoin(params.ElaAssetId, 1, big.NewInt(100000000)),
+	}
+	outputs := []*types.Output{tokenOutput(assetID, tokens("4")), tokenOutput(assetID, tokens("5"))}
+
+	// The change would be the third output of the asset.
+	_, err := s.Fund(coins, &Request{Outputs: outputs, Change: testAddress})
+	assert.Error(t, err)
+
+	// No change is needed if the coins exactly cover the outputs.
+	outputs[1] = tokenOutput(assetID, tokens("6"))
+	result, err := s.Fund(coins, &Request{Outputs: outputs, Change: testAddress})
+	if assert.NoError(t, err) {
+		for _, change := range result.Changes {
+			assert.Equal(t, params.ElaAssetId, change.AssetID)
+		}
+	}
+}
+
+func TestFundPrivacyFee(t *testing.T) {
+	store, assetID, cleanup := testStore(t)
+	defer cleanup()
+	s := New(&Config{Store: store, ChainParams: &params.MainNetParams})
+
+	other := common.Uint168{0x21, 0x04}
+	coins := []*Coin{
+		testCoin(assetID, 0, tokens("10")),
+		testCoin(params.ElaAssetId, 1, big.NewInt(1000000)),
+		{
+			OutPoint:    types.OutPoint{TxID: common.Uint256{0x02}},
+			ProgramHash: other,
+			AssetID:     params.ElaAssetId,
+			Value:       big.NewInt(100000000),
+			Height:      1,
+		},
+	}
+
+	// The ELA of the token address pays the fee, though the other address
+	// holds more ELA.
+	for i := 0; i < 10; i++ {
+		result, err := s.Fund(coins, &Request{
+			Outputs:  []*types.Output{tokenOutput(assetID, tokens("10"))},
+			Change:   testAddress,
+			Strategy: &Privacy{},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, c := range result.Inputs {
+			assert.Equal(t, testAddress, c.ProgramHash)
+		}
+	}
+}
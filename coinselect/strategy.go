@@ -0,0 +1,284 @@
+package coinselect
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// Names of the selection strategies.
+const (
+	LargestFirstName   = "largestfirst"
+	BranchAndBoundName = "branchandbound"
+	PrivacyName        = "privacy"
+)
+
+// defaultMaxTries is the default number of branches the branch and bound
+// strategy visits before giving up.
+const defaultMaxTries = 100000
+
+// Target is the value to select from the coins of an asset.
+type Target struct {
+	// Value is the value the selected coins must cover.
+	Value *big.Int
+
+	// MinChange is the minimum value of the change output, a selection
+	// leaving less change is invalid unless the change can be dropped.
+	MinChange *big.Int
+
+	// MaxDrop is the maximum excess that is dropped instead of paid to a
+	// change output.  It is zero for tokens since the token balance of a
+	// transaction must be exact, and the excess of ELA becomes fee.
+	MaxDrop *big.Int
+
+	// Linked is the addresses already spent by the transaction, spending
+	// more of their coins links no other address.
+	Linked map[common.Uint168]struct{}
+}
+
+// linked returns if the address is already spent by the transaction.
+func (t *Target) linked(programHash common.Uint168) bool {
+	_, ok := t.Linked[programHash]
+	return ok
+}
+
+// Change returns the change of the selection total, zero if there is no
+// change or it is dropped.  ok is false if the total can not be accepted.
+func (t *Target) Change(total *big.Int) (change *big.Int, ok bool) {
+	excess := new(big.Int).Sub(total, t.Value)
+	switch {
+	case excess.Sign() < 0:
+		return nil, false
+	case excess.Sign() == 0:
+		return excess, true
+	case t.MaxDrop != nil && excess.Cmp(t.MaxDrop) <= 0:
+		return new(big.Int), true
+	case t.MinChange == nil || excess.Cmp(t.MinChange) >= 0:
+		return excess, true
+	}
+	return nil, false
+}
+
+// Strategy selects coins of an asset to cover the target.  The coins are all
+// of the same asset and spendable, and the strategy must not modify the slice.
+type Strategy interface {
+	Select(coins []*Coin, target *Target) ([]*Coin, error)
+}
+
+// ParseStrategy returns the strategy of the name, the default is largest
+// first.
+func ParseStrategy(name string) (Strategy, error) {
+	switch name {
+	case "", LargestFirstName:
+		return LargestFirst{}, nil
+	case BranchAndBoundName:
+		return &BranchAndBound{}, nil
+	case PrivacyName:
+		return &Privacy{}, nil
+	}
+	return nil, fmt.Errorf("unknown coin selection strategy %s", name)
+}
+
+func total(coins []*Coin) *big.Int {
+	sum := new(big.Int)
+	for _, c := range coins {
+		sum.Add(sum, c.Value)
+	}
+	return sum
+}
+
+// sortedDesc returns a copy of the coins from the largest, ties are broken by
+// outpoint so the order is deterministic.
+func sortedDesc(coins []*Coin) []*Coin {
+	sorted := append([]*Coin{}, coins...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := sorted[i].Value.Cmp(sorted[j].Value); c != 0 {
+			return c > 0
+		}
+		return lessOutPoint(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+func lessOutPoint(a, b *Coin) bool {
+	if a.OutPoint.TxID != b.OutPoint.TxID {
+		return a.OutPoint.TxID.String() < b.OutPoint.TxID.String()
+	}
+	return a.OutPoint.Index < b.OutPoint.Index
+}
+
+// accumulate selects the coins in order until the target accepts the total.
+func accumulate(coins []*Coin, target *Target) ([]*Coin, error) {
+	sum := new(big.Int)
+	for i, c := range coins {
+		sum.Add(sum, c.Value)
+		if _, ok := target.Change(sum); ok {
+			return coins[:i+1], nil
+		}
+	}
+	if sum.Cmp(target.Value) >= 0 {
+		return nil, ErrDustChange
+	}
+	return nil, ErrInsufficientFunds
+}
+
+// LargestFirst selects the largest coins first, it spends the fewest inputs
+// but consolidates little.
+type LargestFirst struct{}
+
+func (LargestFirst) Select(coins []*Coin, target *Target) ([]*Coin, error) {
+	return accumulate(sortedDesc(coins), target)
+}
+
+// BranchAndBound searches for a selection without change, the total of which
+// is between the target value and the value plus MaxDrop.  The selection
+// with the least excess is chosen, and the largest first selection is used if
+// there is none.
+type BranchAndBound struct {
+	// MaxTries is the maximum number of branches to visit, the default is
+	// used if it is zero.
+	MaxTries int
+}
+
+func (b *BranchAndBound) Select(coins []*Coin, target *Target) ([]*Coin, error) {
+	sorted := sortedDesc(coins)
+	upper := new(big.Int).Set(target.Value)
+	if target.MaxDrop != nil {
+		upper.Add(upper, target.MaxDrop)
+	}
+
+	// remaining[i] is the total of the coins from i.
+	remaining := make([]*big.Int, len(sorted)+1)
+	remaining[len(sorted)] = new(big.Int)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = new(big.Int).Add(remaining[i+1], sorted[i].Value)
+	}
+
+	tries := b.MaxTries
+	if tries <= 0 {
+		tries = defaultMaxTries
+	}
+	var best []*Coin
+	var bestExcess *big.Int
+	var selected []*Coin
+	var search func(i int, sum *big.Int) bool
+	search = func(i int, sum *big.Int) bool {
+		if tries--; tries < 0 {
+			return true
+		}
+		if sum.Cmp(upper) > 0 {
+			return false
+		}
+		if sum.Cmp(target.Value) >= 0 {
+			excess := new(big.Int).Sub(sum, target.Value)
+			if bestExcess == nil || excess.Cmp(bestExcess) < 0 {
+				best, bestExcess = append([]*Coin{}, selected...), excess
+			}
+			return excess.Sign() == 0
+		}
+		if i == len(sorted) || new(big.Int).Add(sum, remaining[i]).Cmp(target.Value) < 0 {
+			return false
+		}
+
+		selected = append(selected, sorted[i])
+		done := search(i+1, new(big.Int).Add(sum, sorted[i].Value))
+		selected = selected[:len(selected)-1]
+		if done {
+			return true
+		}
+		return search(i+1, sum)
+	}
+	search(0, new(big.Int))
+
+	if best != nil {
+		return best, nil
+	}
+	return LargestFirst{}.Select(coins, target)
+}
+
+// Privacy avoids linking addresses by spending coins of a single address when
+// it can, the address is chosen at random among those able to cover the
+// target, and its coins are spent in random order.  Otherwise, the addresses
+// with the largest totals are merged until the target is covered.  Addresses
+// linked by the transaction already are preferred in both cases, so the fee
+// is paid by the address spending the tokens if it holds enough ELA.
+type Privacy struct {
+	// Rand is the source of randomness, a randomly seeded one is used if it
+	// is nil.
+	Rand *rand.Rand
+}
+
+func (p *Privacy) random() *rand.Rand {
+	if p.Rand != nil {
+		return p.Rand
+	}
+	var seed [8]byte
+	crand.Read(seed[:])
+	return rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:]))))
+}
+
+func (p *Privacy) Select(coins []*Coin, target *Target) ([]*Coin, error) {
+	r := p.random()
+
+	groups := make(map[common.Uint168][]*Coin)
+	for _, c := range coins {
+		groups[c.ProgramHash] = append(groups[c.ProgramHash], c)
+	}
+	addresses := make([]common.Uint168, 0, len(groups))
+	for programHash := range groups {
+		addresses = append(addresses, programHash)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		if li, lj := target.linked(addresses[i]), target.linked(addresses[j]); li != lj {
+			return li
+		}
+		if c := total(groups[addresses[i]]).Cmp(total(groups[addresses[j]])); c != 0 {
+			return c > 0
+		}
+		return bytes.Compare(addresses[i][:], addresses[j][:]) < 0
+	})
+
+	// Choose a single address at random, among the linked ones if any of
+	// them can cover the target.
+	var candidates []common.Uint168
+	for _, linked := range []bool{true, false} {
+		for _, programHash := range addresses {
+			if target.linked(programHash) != linked {
+				continue
+			}
+			if _, err := (LargestFirst{}).Select(groups[programHash], target); err == nil {
+				candidates = append(candidates, programHash)
+			}
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+	if len(candidates) > 0 {
+		own := groups[candidates[r.Intn(len(candidates))]]
+		group := make([]*Coin, len(own))
+		for i, j := range r.Perm(len(own)) {
+			group[i] = own[j]
+		}
+		if selected, err := accumulate(group, target); err == nil {
+			return selected, nil
+		}
+		return LargestFirst{}.Select(group, target)
+	}
+
+	// Merge the fewest addresses.
+	var merged []*Coin
+	for _, programHash := range addresses {
+		merged = append(merged, groups[programHash]...)
+		if selected, err := (LargestFirst{}).Select(merged, target); err == nil {
+			return selected, nil
+		}
+	}
+	return LargestFirst{}.Select(coins, target)
+}
@@ -0,0 +1,150 @@
+package coinselect
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCoins(address byte, values ...int64) []*Coin {
+	coins := make([]*Coin, 0, len(values))
+	for i, v := range values {
+		coins = append(coins, &Coin{
+			OutPoint:    types.OutPoint{TxID: common.Uint256{address}, Index: uint16(i)},
+			ProgramHash: common.Uint168{address},
+			Value:       big.NewInt(v),
+		})
+	}
+	return coins
+}
+
+func values(coins []*Coin) []int64 {
+	result := make([]int64, 0, len(coins))
+	for _, c := range coins {
+		result = append(result, c.Value.Int64())
+	}
+	return result
+}
+
+func TestTargetChange(t *testing.T) {
+	target := &Target{Value: big.NewInt(100), MinChange: big.NewInt(10), MaxDrop: big.NewInt(3)}
+
+	_, ok := target.Change(big.NewInt(99))
+	assert.False(t, ok)
+	change, ok := target.Change(big.NewInt(100))
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), change.Int64())
+	change, ok = target.Change(big.NewInt(103))
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), change.Int64())
+	_, ok = target.Change(big.NewInt(105))
+	assert.False(t, ok)
+	change, ok = target.Change(big.NewInt(110))
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), change.Int64())
+}
+
+func TestLargestFirst(t *testing.T) {
+	coins := newCoins(1, 10, 50, 30)
+
+	selected, err := LargestFirst{}.Select(coins, &Target{Value: big.NewInt(40)})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{50}, values(selected))
+
+	// The change 5 is dust, so one more coin is selected.
+	selected, err = LargestFirst{}.Select(coins, &Target{Value: big.NewInt(45), MinChange: big.NewInt(10)})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{50, 30}, values(selected))
+
+	_, err = LargestFirst{}.Select(coins, &Target{Value: big.NewInt(85), MinChange: big.NewInt(10)})
+	assert.Equal(t, ErrDustChange, err)
+	_, err = LargestFirst{}.Select(coins, &Target{Value: big.NewInt(100)})
+	assert.Equal(t, ErrInsufficientFunds, err)
+
+	// The coins are not reordered.
+	assert.Equal(t, []int64{10, 50, 30}, values(coins))
+}
+
+func TestBranchAndBound(t *testing.T) {
+	coins := newCoins(1, 50, 30, 20, 7, 3)
+	bnb := &BranchAndBound{}
+
+	// An exact match of the token target leaves no change.
+	selected, err := bnb.Select(coins, &Target{Value: big.NewInt(60), MaxDrop: new(big.Int)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(60), total(selected).Int64())
+
+	selected, err = bnb.Select(coins, &Target{Value: big.NewInt(57), MaxDrop: new(big.Int)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(57), total(selected).Int64())
+
+	// The excess within MaxDrop is accepted without change.
+	selected, err = bnb.Select(coins, &Target{Value: big.NewInt(81), MaxDrop: big.NewInt(2)})
+	assert.NoError(t, err)
+	change, ok := (&Target{Value: big.NewInt(81), MaxDrop: big.NewInt(2)}).Change(total(selected))
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), change.Int64())
+
+	// Falls back to largest first if no selection leaves no change.
+	selected, err = bnb.Select(newCoins(1, 50, 30), &Target{Value: big.NewInt(45), MaxDrop: new(big.Int)})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{50}, values(selected))
+
+	_, err = bnb.Select(coins, &Target{Value: big.NewInt(200)})
+	assert.Equal(t, ErrInsufficientFunds, err)
+}
+
+func TestPrivacy(t *testing.T) {
+	p := &Privacy{Rand: rand.New(rand.NewSource(1))}
+
+	// Either address can cover the target alone, so only one is spent.
+	coins := append(newCoins(1, 40, 30), newCoins(2, 60, 5)...)
+	for i := 0; i < 20; i++ {
+		selected, err := p.Select(coins, &Target{Value: big.NewInt(50)})
+		assert.NoError(t, err)
+		addresses := make(map[common.Uint168]struct{})
+		for _, c := range selected {
+			addresses[c.ProgramHash] = struct{}{}
+		}
+		assert.Equal(t, 1, len(addresses))
+		assert.True(t, total(selected).Int64() >= 50)
+	}
+
+	// No address can cover the target alone, the address with the largest
+	// total is merged first.
+	coins = append(append(newCoins(1, 40), newCoins(2, 30, 30)...), newCoins(3, 10)...)
+	selected, err := p.Select(coins, &Target{Value: big.NewInt(90)})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{40, 30, 30}, values(selected))
+
+	_, err = p.Select(coins, &Target{Value: big.NewInt(200)})
+	assert.Equal(t, ErrInsufficientFunds, err)
+
+	// The linked address is spent if it can cover the target alone, or
+	// merged first otherwise.
+	coins = append(newCoins(1, 40, 30), newCoins(2, 60, 5)...)
+	linked := map[common.Uint168]struct{}{{2}: {}}
+	for i := 0; i < 20; i++ {
+		selected, err := p.Select(coins, &Target{Value: big.NewInt(50), Linked: linked})
+		assert.NoError(t, err)
+		for _, c := range selected {
+			assert.Equal(t, common.Uint168{2}, c.ProgramHash)
+		}
+	}
+	selected, err = p.Select(coins, &Target{Value: big.NewInt(100), Linked: linked})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{60, 40}, values(selected))
+}
+
+func TestParseStrategy(t *testing.T) {
+	for _, name := range []string{"", LargestFirstName, BranchAndBoundName, PrivacyName} {
+		_, err := ParseStrategy(name)
+		assert.NoError(t, err)
+	}
+	_, err := ParseStrategy("random")
+	assert.Error(t, err)
+}
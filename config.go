@@ -17,6 +17,20 @@ const (
 	configFilename  = "./config.json"
 	defaultLogDir   = "logs"
 	defaultLogLevel = elalog.LevelInfo
+
+	defaultTokenDustUnits          = 1000
+	defaultMaxTokenOutputsPerAsset = 100
+	defaultTxPoolExpiry            = 72
+	defaultMaxTxPoolSize           = 100
+	defaultMaxTxPoolCount          = 50000
+	defaultMinRelayFeeHalfLife     = 720
+	defaultMaxOrphanTxs            = 1000
+	defaultMaxOrphanTxsPerPeer     = 100
+	defaultOrphanTxExpiry          = 20
+	defaultMaxTxsPerAddress        = 100
+	defaultMaxTxSizePerAddress     = 1000
+	defaultMaxTxsPerAsset          = 5000
+	defaultMaxTxSizePerAsset       = 10000
 )
 
 var (
@@ -26,9 +40,25 @@ var (
 	// activeNetParams defines the side chain network parameters.
 	activeNetParams = &params.MainNetParams
 
+	// activeAssetParams defines the asset registration rules of the network.
+	activeAssetParams = &params.MainNetAssetParams
+
 	// defaultConfig defines the default configuration parameters.
 	defaultConfig = configParams{
-		LogLevel: defaultLogLevel,
+		LogLevel:                defaultLogLevel,
+		TokenDustUnits:          defaultTokenDustUnits,
+		MaxTokenOutputsPerAsset: defaultMaxTokenOutputsPerAsset,
+		TxPoolExpiry:            defaultTxPoolExpiry,
+		MaxTxPoolSize:           defaultMaxTxPoolSize,
+		MaxTxPoolCount:          defaultMaxTxPoolCount,
+		MinRelayFeeHalfLife:     defaultMinRelayFeeHalfLife,
+		MaxOrphanTxs:            defaultMaxOrphanTxs,
+		MaxOrphanTxsPerPeer:     defaultMaxOrphanTxsPerPeer,
+		OrphanTxExpiry:          defaultOrphanTxExpiry,
+		MaxTxsPerAddress:        defaultMaxTxsPerAddress,
+		MaxTxSizePerAddress:     defaultMaxTxSizePerAddress,
+		MaxTxsPerAsset:          defaultMaxTxsPerAsset,
+		MaxTxSizePerAsset:       defaultMaxTxSizePerAsset,
 	}
 
 	// cfg indicates the configuration parameters load from 'config.json' file.
@@ -47,6 +77,13 @@ type configParams struct {
 	SPVDNSSeeds        []string
 	SPVDisableDNS      bool
 	SPVPermanentPeers  []string
+	EnableREST         bool
+	RESTPort           uint16
+	EnableWS           bool
+	WSPort             uint16
+	WSAllowedOrigins   []string
+	EnableWallet       bool
+	WalletFile         string
 	EnableRPC          bool
 	RPCPort            uint16
 	RPCUser            string
@@ -63,6 +100,20 @@ type configParams struct {
 	InstantBlock       bool
 	PayToAddr          string
 	MinerInfo          string
+
+	TokenDustUnits          int64
+	MaxTokenOutputsPerAsset int
+	TxPoolExpiry            uint32
+	MaxTxPoolSize           int
+	MaxTxPoolCount          int
+	MinRelayFeeHalfLife     uint32
+	MaxOrphanTxs            int
+	MaxOrphanTxsPerPeer     int
+	OrphanTxExpiry          uint32
+	MaxTxsPerAddress        int
+	MaxTxSizePerAddress     int
+	MaxTxsPerAsset          int
+	MaxTxSizePerAsset       int
 }
 
 // loadConfigFile read configuration parameters through the config.json file.
@@ -89,16 +140,19 @@ func loadConfig() *configParams {
 		testNetDefault(cfg)
 		spvNetParams = config.DefaultParams.TestNet()
 		activeNetParams = &params.TestNetParams
+		activeAssetParams = &params.TestNetAssetParams
 
 	case "regnet", "reg", "r":
 		regNetDefault(cfg)
 		spvNetParams = config.DefaultParams.RegNet()
 		activeNetParams = &params.RegNetParams
+		activeAssetParams = &params.RegNetAssetParams
 
 	default:
 		mainNetDefault(cfg)
 		spvNetParams = &config.DefaultParams
 		activeNetParams = &params.MainNetParams
+		activeAssetParams = &params.MainNetAssetParams
 
 	}
 
@@ -152,6 +206,12 @@ func mainNetDefault(cfg *configParams) {
 	if cfg.RPCPort == 0 {
 		cfg.RPCPort = 20616
 	}
+	if cfg.RESTPort == 0 {
+		cfg.RESTPort = 20614
+	}
+	if cfg.WSPort == 0 {
+		cfg.WSPort = 20615
+	}
 }
 
 // testNetDefault set the default parameters for test network usage.
@@ -159,6 +219,12 @@ func testNetDefault(cfg *configParams) {
 	if cfg.RPCPort == 0 {
 		cfg.RPCPort = 21616
 	}
+	if cfg.RESTPort == 0 {
+		cfg.RESTPort = 21614
+	}
+	if cfg.WSPort == 0 {
+		cfg.WSPort = 21615
+	}
 }
 
 // regNetDefault set the default parameters for regression network usage.
@@ -166,4 +232,10 @@ func regNetDefault(cfg *configParams) {
 	if cfg.RPCPort == 0 {
 		cfg.RPCPort = 22616
 	}
+	if cfg.RESTPort == 0 {
+		cfg.RESTPort = 22614
+	}
+	if cfg.WSPort == 0 {
+		cfg.WSPort = 22615
+	}
 }
@@ -0,0 +1,204 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+const (
+	// TokenPrecision is the number of decimal places of all token values.
+	// Token values are always scaled by 10^TokenPrecision, the precision of an
+	// asset only limits how many of the decimal places can be non-zero.
+	TokenPrecision = 18
+)
+
+// tokenUnits caches the powers of ten by precision to avoid the overhead of
+// creating them multiple times.
+var tokenUnits [TokenPrecision + 1]*big.Int
+
+func init() {
+	for i := range tokenUnits {
+		tokenUnits[i] = new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(i)), nil)
+	}
+}
+
+// TokenAmount represents a token value in base units.
+type TokenAmount struct {
+	value big.Int
+}
+
+// NewTokenAmount returns a token amount holds a copy of the given value.
+func NewTokenAmount(value *big.Int) *TokenAmount {
+	a := new(TokenAmount)
+	a.value.Set(value)
+	return a
+}
+
+// TokenAmountFromUnits returns the token amount of the given number of whole
+// tokens.
+func TokenAmountFromUnits(units int64) *TokenAmount {
+	a := new(TokenAmount)
+	a.value.Mul(big.NewInt(units), tokenUnits[TokenPrecision])
+	return a
+}
+
+// TokenAmountFromBytes returns the token amount of the given big-endian bytes.
+func TokenAmountFromBytes(data []byte) (*TokenAmount, error) {
+	if len(data) > MaxTokenValueDataSize {
+		return nil, fmt.Errorf("token value data size %d exceeds limit %d",
+			len(data), MaxTokenValueDataSize)
+	}
+	a := new(TokenAmount)
+	a.value.SetBytes(data)
+	return a, nil
+}
+
+// ParseTokenAmount parses a decimal string like "1.05" into a token amount,
+// the decimal places of the string can not be more than the given precision.
+func ParseTokenAmount(s string, precision byte) (*TokenAmount, error) {
+	if precision > TokenPrecision {
+		return nil, fmt.Errorf("invalid precision %d", precision)
+	}
+
+	integer, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		integer, fraction = s[:i], s[i+1:]
+	}
+	if len(integer) == 0 && len(fraction) == 0 {
+		return nil, fmt.Errorf("invalid token amount %q", s)
+	}
+	if len(fraction) > int(precision) {
+		return nil, fmt.Errorf("token amount %s out of precision %d", s, precision)
+	}
+	digits := integer + fraction + strings.Repeat("0", TokenPrecision-len(fraction))
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("invalid token amount %q", s)
+		}
+	}
+
+	a := new(TokenAmount)
+	a.value.SetString(digits, 10)
+	if err := a.Check(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Check returns an error if the token amount is negative or it's bytes
+// exceeds MaxTokenValueDataSize.
+func (a *TokenAmount) Check() error {
+	if a.value.Sign() < 0 {
+		return errors.New("token amount is negative")
+	}
+	if len(a.value.Bytes()) > MaxTokenValueDataSize {
+		return errors.New("token amount exceeds the max token value data size")
+	}
+	return nil
+}
+
+// IsPrecise returns if the token amount has no more non-zero decimal places
+// than the given precision.
+func (a *TokenAmount) IsPrecise(precision byte) bool {
+	if precision >= TokenPrecision {
+		return true
+	}
+	var mod big.Int
+	return mod.Mod(&a.value, tokenUnits[TokenPrecision-precision]).Sign() == 0
+}
+
+// Int returns a copy of the token amount in base units.
+func (a *TokenAmount) Int() *big.Int {
+	return new(big.Int).Set(&a.value)
+}
+
+// Bytes returns the absolute value of the token amount as big-endian bytes.
+func (a *TokenAmount) Bytes() []byte {
+	return a.value.Bytes()
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the token amount.
+func (a *TokenAmount) Sign() int {
+	return a.value.Sign()
+}
+
+// Cmp compares the token amount with the given one.
+func (a *TokenAmount) Cmp(b *TokenAmount) int {
+	return a.value.Cmp(&b.value)
+}
+
+// Add sets the token amount to the sum of itself and b, and returns it.
+func (a *TokenAmount) Add(b *TokenAmount) *TokenAmount {
+	a.value.Add(&a.value, &b.value)
+	return a
+}
+
+// Sub sets the token amount to the difference of itself and b, and returns it.
+func (a *TokenAmount) Sub(b *TokenAmount) *TokenAmount {
+	a.value.Sub(&a.value, &b.value)
+	return a
+}
+
+// String returns the token amount as a decimal string with all the
+// TokenPrecision decimal places, or "0" if the amount is zero.
+func (a *TokenAmount) String() string {
+	if a.value.Sign() == 0 {
+		return "0"
+	}
+	return a.format(TokenPrecision)
+}
+
+// Format returns the token amount as a decimal string with the given number of
+// decimal places.  More decimal places are kept if they are not all zeros, so
+// the result is always exact.
+func (a *TokenAmount) Format(precision byte) string {
+	if precision > TokenPrecision {
+		precision = TokenPrecision
+	}
+	for precision < TokenPrecision && !a.IsPrecise(precision) {
+		precision++
+	}
+	return a.format(int(precision))
+}
+
+func (a *TokenAmount) format(places int) string {
+	var abs big.Int
+	abs.Abs(&a.value)
+	digits := abs.String()
+	if len(digits) <= TokenPrecision {
+		digits = strings.Repeat("0", TokenPrecision-len(digits)+1) + digits
+	}
+
+	sign := ""
+	if a.value.Sign() < 0 {
+		sign = "-"
+	}
+	point := len(digits) - TokenPrecision
+	if places == 0 {
+		return sign + digits[:point]
+	}
+	return sign + digits[:point] + "." + digits[point:point+places]
+}
+
+// Serialize writes the token amount as var bytes.
+func (a *TokenAmount) Serialize(w io.Writer) error {
+	if err := a.Check(); err != nil {
+		return err
+	}
+	return common.WriteVarBytes(w, a.value.Bytes())
+}
+
+// Deserialize reads the token amount from var bytes.
+func (a *TokenAmount) Deserialize(r io.Reader) error {
+	data, err := common.ReadVarBytes(r, MaxTokenValueDataSize, "TokenValue")
+	if err != nil {
+		return err
+	}
+	a.value.SetBytes(data)
+	return nil
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTokenAmount(t *testing.T) {
+	amount, err := ParseTokenAmount("1.05", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "1050000000000000000", amount.Int().String())
+
+	amount, err = ParseTokenAmount("0.000000000000000001", 18)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", amount.Int().String())
+
+	amount, err = ParseTokenAmount("12", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, TokenAmountFromUnits(12).Int(), amount.Int())
+
+	amount, err = ParseTokenAmount(".5", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.5", amount.Format(1))
+
+	invalids := []struct {
+		value     string
+		precision byte
+	}{
+		{"1.005", 2},
+		{"1.5", 0},
+		{"-1", 8},
+		{"1e8", 8},
+		{"1.2.3", 8},
+		{".", 8},
+		{"", 8},
+		{"1", 19},
+		{strings.Repeat("9", 80), 0},
+	}
+	for _, v := range invalids {
+		_, err := ParseTokenAmount(v.value, v.precision)
+		assert.Error(t, err, "%s with precision %d should be invalid", v.value, v.precision)
+	}
+}
+
+func TestTokenAmountFormat(t *testing.T) {
+	amount, _ := ParseTokenAmount("0.5", 18)
+	assert.Equal(t, "0.500000000000000000", amount.String())
+	assert.Equal(t, "0.50", amount.Format(2))
+	assert.Equal(t, "0.5", amount.Format(0))
+
+	amount, _ = ParseTokenAmount("123456789.000000000000000001", 18)
+	assert.Equal(t, "123456789.000000000000000001", amount.String())
+	assert.Equal(t, "123456789.000000000000000001", amount.Format(8))
+
+	assert.Equal(t, "0", new(TokenAmount).String())
+	assert.Equal(t, "0.0000", new(TokenAmount).Format(4))
+	assert.Equal(t, "7", TokenAmountFromUnits(7).Format(0))
+}
+
+func TestTokenAmountIsPrecise(t *testing.T) {
+	amount, _ := ParseTokenAmount("1.25", 18)
+	assert.True(t, amount.IsPrecise(2))
+	assert.True(t, amount.IsPrecise(18))
+	assert.False(t, amount.IsPrecise(1))
+	assert.False(t, amount.IsPrecise(0))
+}
+
+func TestTokenAmountSerialize(t *testing.T) {
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), MaxTokenValueDataSize*8), big.NewInt(1))
+	for _, v := range []*big.Int{big.NewInt(0), big.NewInt(1), max} {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, NewTokenAmount(v).Serialize(buf))
+
+		var amount TokenAmount
+		assert.NoError(t, amount.Deserialize(buf))
+		assert.Equal(t, 0, v.Cmp(amount.Int()))
+	}
+
+	overflow := new(big.Int).Lsh(big.NewInt(1), MaxTokenValueDataSize*8)
+	assert.Error(t, NewTokenAmount(overflow).Serialize(new(bytes.Buffer)))
+	assert.Error(t, NewTokenAmount(big.NewInt(-1)).Serialize(new(bytes.Buffer)))
+
+	_, err := TokenAmountFromBytes(overflow.Bytes())
+	assert.Error(t, err)
+}
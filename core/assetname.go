@@ -0,0 +1,25 @@
+package core
+
+import (
+	"strings"
+)
+
+// confusables maps the character sequences that look alike in asset names to
+// a same canonical form, the keys are matched after lower case folding.
+var confusables = strings.NewReplacer(
+	"rn", "m",
+	"vv", "w",
+	"0", "o",
+	"1", "l",
+	"i", "l",
+	"5", "s",
+	"8", "b",
+)
+
+// CanonicalAssetName returns the canonical form of an asset name which is used
+// to check the uniqueness of asset names.  Letters are folded to lower case and
+// the confusable characters like 0/O and 1/l/I are folded to the same one, so
+// asset names that look alike have the same canonical form.
+func CanonicalAssetName(name string) string {
+	return confusables.Replace(strings.ToLower(name))
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalAssetName(t *testing.T) {
+	same := [][]string{
+		{"USDT", "usdt", "USDt", "U5DT"},
+		{"GOLD", "G0LD", "gold"},
+		{"ELA", "E1A", "EIA", "ela"},
+		{"Modern", "Modem", "rnodern"},
+	}
+	for _, names := range same {
+		for _, name := range names[1:] {
+			assert.Equal(t, CanonicalAssetName(names[0]), CanonicalAssetName(name),
+				"%s should be confusable with %s", name, names[0])
+		}
+	}
+
+	different := [][]string{
+		{"USDT", "USDC"},
+		{"ELA", "ELB"},
+		{"TOKEN1", "TOKEN2"},
+	}
+	for _, names := range different {
+		assert.NotEqual(t, CanonicalAssetName(names[0]), CanonicalAssetName(names[1]))
+	}
+}
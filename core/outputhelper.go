@@ -1,6 +1,8 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"io"
 
 	"github.com/elastos/Elastos.ELA.SideChain/types"
@@ -24,7 +26,7 @@ func serializeOutput(output *types.Output, w io.Writer) error {
 			return err
 		}
 	} else {
-		err = WriteVarBytes(w, output.TokenValue.Bytes())
+		err = NewTokenAmount(&output.TokenValue).Serialize(w)
 		if err != nil {
 			return err
 		}
@@ -52,11 +54,11 @@ func deserializeOutput(output *types.Output, r io.Reader) error {
 			return err
 		}
 	} else {
-		bytes, err := ReadVarBytes(r, MaxTokenValueDataSize, "TokenValue")
-		if err != nil {
+		var amount TokenAmount
+		if err := amount.Deserialize(r); err != nil {
 			return err
 		}
-		output.TokenValue.SetBytes(bytes)
+		output.TokenValue.Set(&amount.value)
 	}
 
 	temp, err := ReadUint32(r)
@@ -73,6 +75,56 @@ func deserializeOutput(output *types.Output, r io.Reader) error {
 	return nil
 }
 
+// serializeOutputMemo serializes the memo of the output at the index into the
+// data of a memo attribute, which is the marker, the version, the output index
+// and the var bytes memo.
+func serializeOutputMemo(w io.Writer, index uint16, memo []byte) error {
+	if len(memo) == 0 || len(memo) > MaxOutputMemoSize {
+		return fmt.Errorf("memo length %d out of range (0, %d]", len(memo), MaxOutputMemoSize)
+	}
+	if _, err := w.Write([]byte{outputMemoMarker, OutputMemoVersion}); err != nil {
+		return err
+	}
+	if err := WriteUint16(w, index); err != nil {
+		return err
+	}
+	return WriteVarBytes(w, memo)
+}
+
+// deserializeOutputMemo deserializes the output index and memo from the data
+// of a memo attribute, the data must be consumed entirely.
+func deserializeOutputMemo(r io.Reader) (uint16, []byte, error) {
+	marker, err := ReadUint8(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if marker != outputMemoMarker {
+		return 0, nil, errors.New("not an output memo")
+	}
+	version, err := ReadUint8(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if version != OutputMemoVersion {
+		return 0, nil, fmt.Errorf("unknown output memo version %d", version)
+	}
+	index, err := ReadUint16(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	memo, err := ReadVarBytes(r, MaxOutputMemoSize, "memo")
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(memo) == 0 {
+		return 0, nil, errors.New("empty output memo")
+	}
+	if n, _ := r.Read(make([]byte, 1)); n > 0 {
+		return 0, nil, errors.New("unexpected data after output memo")
+	}
+	return index, memo, nil
+}
+
 func Init() {
 	types.SerializeOutput = serializeOutput
 	types.DeserializeOutput = deserializeOutput
@@ -0,0 +1,46 @@
+//go:build go1.18
+// +build go1.18
+
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+)
+
+func FuzzOutput(f *testing.F) {
+	for _, output := range testOutputs() {
+		buf := new(bytes.Buffer)
+		if err := serializeOutput(output, buf); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		output := new(types.Output)
+		if err := deserializeOutput(output, bytes.NewReader(data)); err != nil {
+			return
+		}
+
+		// Decoded outputs can always be encoded, and decoding the encoding
+		// gives the same output.  The encoding may differ from data since
+		// token values with leading zero bytes are normalized.
+		buf := new(bytes.Buffer)
+		if err := serializeOutput(output, buf); err != nil {
+			t.Fatalf("encode decoded output: %v", err)
+		}
+		decoded := new(types.Output)
+		if err := deserializeOutput(decoded, bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatalf("decode encoded output: %v", err)
+		}
+		if output.AssetID != decoded.AssetID || output.Value != decoded.Value ||
+			output.TokenValue.Cmp(&decoded.TokenValue) != 0 ||
+			output.OutputLock != decoded.OutputLock ||
+			output.ProgramHash != decoded.ProgramHash {
+			t.Fatalf("output changed after round trip")
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testTokenID     = common.Uint256{0x01, 0x02, 0x03}
+	testProgramHash = common.Uint168{0x21, 0x01, 0x02}
+)
+
+func maxTokenValue() *big.Int {
+	return new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), MaxTokenValueDataSize*8), big.NewInt(1))
+}
+
+func testOutputs() []*types.Output {
+	ela := &types.Output{
+		AssetID:     types.GetSystemAssetId(),
+		Value:       100000000,
+		OutputLock:  10,
+		ProgramHash: testProgramHash,
+	}
+
+	var outputs = []*types.Output{ela}
+	for _, v := range []*big.Int{big.NewInt(0), big.NewInt(1), maxTokenValue()} {
+		output := &types.Output{
+			AssetID:     testTokenID,
+			OutputLock:  20,
+			ProgramHash: testProgramHash,
+		}
+		output.TokenValue.Set(v)
+		outputs = append(outputs, output)
+	}
+	return outputs
+}
+
+func assertOutputEqual(t *testing.T, expected, actual *types.Output) {
+	assert.Equal(t, expected.AssetID, actual.AssetID)
+	assert.Equal(t, expected.Value, actual.Value)
+	assert.Equal(t, 0, expected.TokenValue.Cmp(&actual.TokenValue))
+	assert.Equal(t, expected.OutputLock, actual.OutputLock)
+	assert.Equal(t, expected.ProgramHash, actual.ProgramHash)
+}
+
+func TestOutputRoundTrip(t *testing.T) {
+	for _, output := range testOutputs() {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, serializeOutput(output, buf))
+		data := buf.Bytes()
+
+		decoded := new(types.Output)
+		assert.NoError(t, deserializeOutput(decoded, bytes.NewReader(data)))
+		assertOutputEqual(t, output, decoded)
+
+		// The encoding is stable after decoding.
+		buf = new(bytes.Buffer)
+		assert.NoError(t, serializeOutput(decoded, buf))
+		assert.Equal(t, data, buf.Bytes())
+
+		// Truncated data can never be decoded.
+		for i := 0; i < len(data); i++ {
+			assert.Error(t, deserializeOutput(new(types.Output), bytes.NewReader(data[:i])))
+		}
+	}
+}
+
+func TestOutputLegacyLayout(t *testing.T) {
+	output := &types.Output{AssetID: testTokenID, ProgramHash: testProgramHash}
+	output.TokenValue.SetInt64(1)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, serializeOutput(output, buf))
+
+	// AssetID, var bytes token value, OutputLock and ProgramHash.
+	expected := new(bytes.Buffer)
+	testTokenID.Serialize(expected)
+	expected.Write([]byte{0x01, 0x01})
+	common.WriteUint32(expected, 0)
+	testProgramHash.Serialize(expected)
+	assert.Equal(t, expected.Bytes(), buf.Bytes())
+}
+
+func TestOutputInvalid(t *testing.T) {
+	// Token value exceeds MaxTokenValueDataSize.
+	output := &types.Output{AssetID: testTokenID, ProgramHash: testProgramHash}
+	output.TokenValue.Add(maxTokenValue(), big.NewInt(1))
+	assert.Error(t, serializeOutput(output, new(bytes.Buffer)))
+
+	buf := new(bytes.Buffer)
+	testTokenID.Serialize(buf)
+	common.WriteVarBytes(buf, make([]byte, MaxTokenValueDataSize+1))
+	common.WriteUint32(buf, 0)
+	testProgramHash.Serialize(buf)
+	assert.Error(t, deserializeOutput(new(types.Output), buf))
+}
+
+func TestOutputMemos(t *testing.T) {
+	tx := &types.Transaction{
+		Attributes: []*types.Attribute{{Usage: types.Memo, Data: []byte("text memo")}},
+		Outputs:    testOutputs(),
+	}
+	memos, err := OutputMemos(tx)
+	assert.NoError(t, err)
+	assert.Empty(t, memos)
+
+	long := bytes.Repeat([]byte{'m'}, MaxOutputMemoSize)
+	assert.NoError(t, SetOutputMemos(tx, map[int][]byte{3: long, 1: []byte("memo")}))
+	assert.Equal(t, 3, len(tx.Attributes))
+	memos, err = OutputMemos(tx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int][]byte{1: []byte("memo"), 3: long}, memos)
+	assert.Equal(t, long, OutputMemo(tx, 3))
+	assert.Nil(t, OutputMemo(tx, 2))
+
+	// Setting the memo again replaces it, an empty memo removes it.
+	assert.NoError(t, SetOutputMemo(tx, 1, []byte("new")))
+	assert.Equal(t, []byte("new"), OutputMemo(tx, 1))
+	assert.NoError(t, SetOutputMemo(tx, 3, nil))
+	assert.Nil(t, OutputMemo(tx, 3))
+	assert.Equal(t, 2, len(tx.Attributes))
+
+	assert.Error(t, SetOutputMemo(tx, 2, make([]byte, MaxOutputMemoSize+1)))
+	assert.Error(t, SetOutputMemo(tx, -1, []byte("memo")))
+}
+
+func TestOutputMemosInvalid(t *testing.T) {
+	memoAttr := func(index uint16, memo []byte) *types.Attribute {
+		buf := new(bytes.Buffer)
+		buf.Write([]byte{outputMemoMarker, OutputMemoVersion})
+		common.WriteUint16(buf, index)
+		common.WriteVarBytes(buf, memo)
+		return &types.Attribute{Usage: types.Memo, Data: buf.Bytes()}
+	}
+	valid := memoAttr(1, []byte("memo"))
+
+	for _, attrs := range [][]*types.Attribute{
+		// ELA output can not carry memo.
+		{memoAttr(0, []byte("memo"))},
+		// Output index out of range.
+		{memoAttr(uint16(len(testOutputs())), []byte("memo"))},
+		// Duplicated memos of an output.
+		{valid, memoAttr(1, []byte("other"))},
+		// Empty or oversize memo.
+		{memoAttr(1, []byte{})},
+		{memoAttr(1, make([]byte, MaxOutputMemoSize+1))},
+		// Unknown version and trailing data.
+		{{Usage: types.Memo, Data: append([]byte{outputMemoMarker, OutputMemoVersion + 1}, valid.Data[2:]...)}},
+		{{Usage: types.Memo, Data: append(append([]byte{}, valid.Data...), 0x00)}},
+	} {
+		_, err := OutputMemos(&types.Transaction{Attributes: attrs, Outputs: testOutputs()})
+		assert.Error(t, err)
+	}
+
+	// Truncated data can never be decoded.
+	for i := 1; i < len(valid.Data); i++ {
+		attr := &types.Attribute{Usage: types.Memo, Data: valid.Data[:i]}
+		_, err := OutputMemos(&types.Transaction{Attributes: []*types.Attribute{attr}, Outputs: testOutputs()})
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+)
+
+const (
+	// MaxOutputMemoSize is the maximum allowed length of an output memo.
+	MaxOutputMemoSize = 256
+
+	// OutputMemoVersion is the version of the output memo attribute.
+	OutputMemoVersion byte = 1
+
+	// outputMemoMarker marks a memo attribute carrying an output memo, it
+	// is never the first byte of a valid UTF-8 text memo.
+	outputMemoMarker byte = 0xfc
+)
+
+// isOutputMemo returns if the attribute carries an output memo.
+func isOutputMemo(attr *types.Attribute) bool {
+	return attr.Usage == types.Memo && len(attr.Data) > 0 && attr.Data[0] == outputMemoMarker
+}
+
+// OutputMemos returns the memos of the token outputs carried by the memo
+// attributes of the transaction, keyed by the output index.
+func OutputMemos(tx *types.Transaction) (map[int][]byte, error) {
+	var memos map[int][]byte
+	for _, attr := range tx.Attributes {
+		if !isOutputMemo(attr) {
+			continue
+		}
+		index, memo, err := deserializeOutputMemo(bytes.NewReader(attr.Data))
+		if err != nil {
+			return nil, err
+		}
+		if int(index) >= len(tx.Outputs) {
+			return nil, fmt.Errorf("memo of output %d out of range", index)
+		}
+		if tx.Outputs[index].AssetID.IsEqual(types.GetSystemAssetId()) {
+			return nil, fmt.Errorf("memo is not supported by ELA output %d", index)
+		}
+		if _, ok := memos[int(index)]; ok {
+			return nil, fmt.Errorf("duplicated memo of output %d", index)
+		}
+		if memos == nil {
+			memos = make(map[int][]byte)
+		}
+		memos[int(index)] = memo
+	}
+	return memos, nil
+}
+
+// OutputMemo returns the memo of the output at the index, or nil if it has no
+// memo or the memos of the transaction are invalid.
+func OutputMemo(tx *types.Transaction, index int) []byte {
+	memos, err := OutputMemos(tx)
+	if err != nil {
+		return nil
+	}
+	return memos[index]
+}
+
+// SetOutputMemo attaches the memo to the output at the index through a memo
+// attribute, an empty memo removes it.
+func SetOutputMemo(tx *types.Transaction, index int, memo []byte) error {
+	if index < 0 || index > math.MaxUint16 {
+		return fmt.Errorf("output index %d out of range", index)
+	}
+	attributes := make([]*types.Attribute, 0, len(tx.Attributes)+1)
+	for _, attr := range tx.Attributes {
+		if isOutputMemo(attr) {
+			i, _, err := deserializeOutputMemo(bytes.NewReader(attr.Data))
+			if err == nil && int(i) == index {
+				continue
+			}
+		}
+		attributes = append(attributes, attr)
+	}
+	if len(memo) > 0 {
+		buf := new(bytes.Buffer)
+		if err := serializeOutputMemo(buf, uint16(index), memo); err != nil {
+			return err
+		}
+		attributes = append(attributes, &types.Attribute{Usage: types.Memo, Data: buf.Bytes()})
+	}
+	tx.Attributes = attributes
+	return nil
+}
+
+// SetOutputMemos attaches the memos keyed by the output index to the outputs,
+// the attributes are appended in the order of the output index.
+func SetOutputMemos(tx *types.Transaction, memos map[int][]byte) error {
+	indexes := make([]int, 0, len(memos))
+	for index := range memos {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		if err := SetOutputMemo(tx, index, memos[index]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package filter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA.SideChain/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain/filter"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// FTAssetID is the filter type of the asset ID filter, it's chosen out of the
+// range of the side chain filter types.
+const FTAssetID filter.TxFilterType = 100
+
+// MaxFilterAssets is the maximum number of asset IDs can be loaded into an
+// asset ID filter.
+const MaxFilterAssets = 1000
+
+// maxFilterOutpoints is the maximum number of matched outpoints remembered by
+// an asset ID filter, spending other outpoints are matched by looking up the
+// referenced transactions.
+const maxFilterOutpoints = 10000
+
+// assetFilter matches transactions which outputs or spent inputs involve the
+// loaded asset IDs.
+type assetFilter struct {
+	store *blockchain.ChainStore
+
+	mtx       sync.Mutex
+	loaded    bool
+	assets    map[common.Uint256]struct{}
+	outpoints map[types.OutPoint]struct{}
+}
+
+// Load loads the asset IDs, the filter is serialized as a var uint count
+// followed by the asset IDs.
+func (f *assetFilter) Load(filter []byte) error {
+	r := bytes.NewReader(filter)
+	count, err := common.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	if count > MaxFilterAssets {
+		return fmt.Errorf("asset filter size %d exceeds limit %d", count, MaxFilterAssets)
+	}
+
+	assets := make(map[common.Uint256]struct{}, count)
+	for i := uint64(0); i < count; i++ {
+		var assetID common.Uint256
+		if err := assetID.Deserialize(r); err != nil {
+			return err
+		}
+		assets[assetID] = struct{}{}
+	}
+
+	f.mtx.Lock()
+	f.loaded = true
+	f.assets = assets
+	f.outpoints = make(map[types.OutPoint]struct{})
+	f.mtx.Unlock()
+
+	return nil
+}
+
+// Add adds an asset ID into the loaded filter.
+func (f *assetFilter) Add(filter []byte) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if !f.loaded {
+		return errors.New("filter not loaded")
+	}
+	if len(f.assets) >= MaxFilterAssets {
+		return fmt.Errorf("asset filter size exceeds limit %d", MaxFilterAssets)
+	}
+
+	assetID, err := common.Uint256FromBytes(filter)
+	if err != nil {
+		return err
+	}
+	f.assets[*assetID] = struct{}{}
+
+	return nil
+}
+
+// Match returns if the transaction registers, transfers or spends any of the
+// loaded assets.  Outpoints of matched outputs are remembered until they are
+// spent, so spending them can be matched without looking up the referenced
+// transactions.
+func (f *assetFilter) Match(tx *types.Transaction) bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if !f.loaded {
+		return false
+	}
+
+	matched := false
+	if payload, ok := tx.Payload.(*types.PayloadRegisterAsset); ok {
+		_, matched = f.assets[payload.Asset.Hash()]
+	}
+
+	for _, input := range tx.Inputs {
+		if !matched && f.matchInput(input) {
+			matched = true
+		}
+		delete(f.outpoints, input.Previous)
+	}
+
+	txID := tx.Hash()
+	for i, output := range tx.Outputs {
+		if _, ok := f.assets[output.AssetID]; ok {
+			matched = true
+			if len(f.outpoints) < maxFilterOutpoints {
+				f.outpoints[types.OutPoint{TxID: txID, Index: uint16(i)}] = struct{}{}
+			}
+		}
+	}
+	return matched
+}
+
+// matchInput returns if the input spends an output of the loaded assets,
+// caller must hold the lock.
+func (f *assetFilter) matchInput(input *types.Input) bool {
+	if _, ok := f.outpoints[input.Previous]; ok {
+		return true
+	}
+	if f.store == nil {
+		return false
+	}
+
+	referTxn, _, err := f.store.GetTransaction(input.Previous.TxID)
+	if err != nil || int(input.Previous.Index) >= len(referTxn.Outputs) {
+		return false
+	}
+	_, ok := f.assets[referTxn.Outputs[input.Previous.Index].AssetID]
+	return ok
+}
+
+// NewAssetFilter returns an asset ID filter, the store is used to find the
+// assets of spent inputs.
+func NewAssetFilter(store *blockchain.ChainStore) filter.TxFilter {
+	return &assetFilter{store: store}
+}
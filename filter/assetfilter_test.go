@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadedFilter(t *testing.T, assets ...common.Uint256) *assetFilter {
+	buf := new(bytes.Buffer)
+	common.WriteVarUint(buf, uint64(len(assets)))
+	for _, assetID := range assets {
+		assetID.Serialize(buf)
+	}
+	f := &assetFilter{}
+	assert.NoError(t, f.Load(buf.Bytes()))
+	return f
+}
+
+func filterTestTx(assetID common.Uint256, inputs ...types.OutPoint) *types.Transaction {
+	tx := &types.Transaction{
+		TxType:  types.TransferAsset,
+		Payload: &types.PayloadTransferAsset{},
+		Outputs: []*types.Output{{AssetID: assetID}},
+	}
+	for _, input := range inputs {
+		tx.Inputs = append(tx.Inputs, &types.Input{Previous: input})
+	}
+	return tx
+}
+
+func TestAssetFilterMatch(t *testing.T) {
+	token := common.Uint256{0x01}
+	other := common.Uint256{0x02}
+	f := loadedFilter(t, token)
+	assert.False(t, (&assetFilter{}).Match(filterTestTx(token)))
+
+	transfer := filterTestTx(token)
+	assert.True(t, f.Match(transfer))
+	assert.False(t, f.Match(filterTestTx(other)))
+
+	// Spending a matched output is matched, and the outpoint is forgotten.
+	outpoint := types.OutPoint{TxID: transfer.Hash()}
+	spend := filterTestTx(other, outpoint)
+	assert.True(t, f.Match(spend))
+	_, ok := f.outpoints[outpoint]
+	assert.False(t, ok)
+	assert.Equal(t, 0, len(f.outpoints))
+	assert.False(t, f.Match(filterTestTx(other, types.OutPoint{TxID: spend.Hash()})))
+
+	assert.NoError(t, f.Add(other.Bytes()))
+	assert.True(t, f.Match(filterTestTx(other)))
+
+	// Registrations are matched by the ID of the registered asset.
+	registration := &types.Transaction{
+		TxType:  types.RegisterAsset,
+		Payload: &types.PayloadRegisterAsset{Asset: types.Asset{Name: "TOKEN"}},
+	}
+	assert.False(t, f.Match(registration))
+	assert.False(t, loadedFilter(t, registration.Hash()).Match(registration))
+	assetID := registration.Payload.(*types.PayloadRegisterAsset).Asset.Hash()
+	assert.True(t, loadedFilter(t, assetID).Match(registration))
+}
+
+func TestAssetFilterOutpointsLimit(t *testing.T) {
+	token := common.Uint256{0x01}
+	f := loadedFilter(t, token)
+
+	tx := filterTestTx(token)
+	for i := 1; i < maxFilterOutpoints+10; i++ {
+		tx.Outputs = append(tx.Outputs, &types.Output{AssetID: token})
+	}
+	assert.True(t, f.Match(tx))
+	assert.Equal(t, maxFilterOutpoints, len(f.outpoints))
+}
@@ -11,9 +11,13 @@ import (
 
 	bc "github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
 	"github.com/elastos/Elastos.ELA.SideChain.Token/bloom"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/coinselect"
 	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	tf "github.com/elastos/Elastos.ELA.SideChain.Token/filter"
 	mp "github.com/elastos/Elastos.ELA.SideChain.Token/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/p2p"
 	sv "github.com/elastos/Elastos.ELA.SideChain.Token/service"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/wallet"
 
 	"github.com/elastos/Elastos.ELA.SideChain/blockchain"
 	"github.com/elastos/Elastos.ELA.SideChain/filter"
@@ -22,6 +26,8 @@ import (
 	"github.com/elastos/Elastos.ELA.SideChain/server"
 	"github.com/elastos/Elastos.ELA.SideChain/service"
 	"github.com/elastos/Elastos.ELA.SideChain/spv"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/p2p/msg"
 	"github.com/elastos/Elastos.ELA/utils/elalog"
 	"github.com/elastos/Elastos.ELA/utils/http/jsonrpc"
 	"github.com/elastos/Elastos.ELA/utils/signal"
@@ -34,6 +40,12 @@ const (
 	DataDir  = "data"
 	ChainDir = "chain"
 	SpvDir   = "spv"
+
+	TxPoolFile = "mempool.dat"
+	WalletFile = "wallet.json"
+
+	// maxOrphanTxSize is the maximum size of an orphan transaction.
+	maxOrphanTxSize = 100000
 )
 
 var (
@@ -63,7 +75,8 @@ func main() {
 
 	eladlog.Info("1. BlockChain init")
 	chainStore, err := bc.NewChainStore(activeNetParams.GenesisBlock,
-		activeNetParams.ElaAssetId, filepath.Join(DataPath, DataDir, ChainDir))
+		activeNetParams.ElaAssetId, activeAssetParams, filepath.Join(DataPath, DataDir, ChainDir),
+		cfg.EnableWallet)
 	if err != nil {
 		eladlog.Fatalf("open chain store failed, %s", err)
 		os.Exit(1)
@@ -104,6 +117,7 @@ func main() {
 		ChainParams: activeNetParams,
 		ChainStore:  chainStore.ChainStore,
 		SpvService:  spvService,
+		AssetParams: activeAssetParams,
 	}
 	txFeeHelper := mp.NewFeeHelper(&mempoolCfg)
 	mempoolCfg.FeeHelper = txFeeHelper
@@ -111,6 +125,35 @@ func main() {
 	txValidator := mp.NewValidator(&mempoolCfg)
 	mempoolCfg.Validator = txValidator
 
+	// The relay validator checks transactions against the relay policies
+	// before they are accepted into the transaction pool.
+	relayCfg := mempoolCfg
+	relayCfg.Policy = &mp.Policy{
+		TokenDustUnits:          cfg.TokenDustUnits,
+		MaxTokenOutputsPerAsset: cfg.MaxTokenOutputsPerAsset,
+		MaxPoolSize:             cfg.MaxTxPoolSize * 1024 * 1024,
+		MaxPoolCount:            cfg.MaxTxPoolCount,
+		FeeRateHalfLife:         time.Duration(cfg.MinRelayFeeHalfLife) * time.Minute,
+		MaxTxsPerAddress:        cfg.MaxTxsPerAddress,
+		MaxBytesPerAddress:      cfg.MaxTxSizePerAddress * 1024,
+		MaxTxsPerAsset:          cfg.MaxTxsPerAsset,
+		MaxBytesPerAsset:        cfg.MaxTxSizePerAsset * 1024,
+	}
+	relayCfg.Orphans = mp.NewOrphanPool(mp.OrphanPolicy{
+		MaxOrphans:       cfg.MaxOrphanTxs,
+		MaxOrphansPerTag: cfg.MaxOrphanTxsPerPeer,
+		MaxOrphanSize:    maxOrphanTxSize,
+		OrphanTTL:        time.Duration(cfg.OrphanTxExpiry) * time.Minute,
+	})
+	relayValidator := mp.NewValidator(&relayCfg)
+	relayCfg.Validator = relayValidator
+
+	// The dry run validator checks transactions like the relay validator,
+	// but never keeps them as orphans.
+	dryRunCfg := relayCfg
+	dryRunCfg.Orphans = nil
+	dryRunValidator := mp.NewValidator(&dryRunCfg)
+
 	chainCfg := blockchain.Config{
 		ChainParams:    activeNetParams,
 		ChainStore:     chainStore.ChainStore,
@@ -129,23 +172,49 @@ func main() {
 	mpCfg := mempool.Config{
 		ChainParams: activeNetParams,
 		ChainStore:  chainStore.ChainStore,
-		Validator:   txValidator,
+		Validator:   relayValidator,
 	}
 	mpCfg.FeeHelper = txFeeHelper.FeeHelper
-	txPool := mempool.New(&mpCfg)
+	// The P2P server, pow and RPC service of the side chain take the side chain
+	// pool, the pool limits are enforced by the checks of the relay validator
+	// which every transaction appended into the pool passes.
+	txPool := mp.NewTxPool(&relayCfg, mempool.New(&mpCfg))
+	txPool.RegisterChecks(dryRunValidator)
+	chainStore.AddListener(txPool)
+
+	// The WebSocket server listens to the block and pool changes, so it is
+	// created before the chain and pool start.
+	var wsServer *sv.WSServer
+	if cfg.EnableWS {
+		wsServer = sv.NewWSServer(cfg.WSPort, cfg.WSAllowedOrigins, chainStore)
+		chainStore.AddListener(wsServer)
+		txPool.AddTxListener(wsServer.OnTxAdded)
+	}
+
+	txPoolFile := filepath.Join(DataPath, DataDir, TxPoolFile)
+	loaded, err := txPool.Load(txPoolFile, time.Duration(cfg.TxPoolExpiry)*time.Hour)
+	if err != nil {
+		eladlog.Warnf("load transaction pool failed, %s", err)
+	}
+	eladlog.Infof("%d transactions loaded into transaction pool", loaded)
 
 	eladlog.Info("3. Start the P2P networks")
+	cfilters := p2p.NewCFilterService(chainStore)
 	server, err := server.New(&server.Config{
-		DataDir:     filepath.Join(DataPath, DataDir),
-		Chain:       chain,
-		TxMemPool:   txPool,
-		ChainParams: activeNetParams,
+		DataDir:          filepath.Join(DataPath, DataDir),
+		Chain:            chain,
+		TxMemPool:        txPool.TxPool,
+		ChainParams:      activeNetParams,
+		MakeEmptyMessage: p2p.MakeEmptyMessage,
+		HandleMessage:    p2p.NewMessageHandler(relayCfg.Orphans, cfilters).HandleMessage,
 		NewTxFilter: func(t filter.TxFilterType) filter.TxFilter {
 			switch t {
 			case filter.FTBloom:
 				return bloom.NewTxFilter()
 			case filter.FTTxType:
 				return filter.NewTxTypeFilter()
+			case tf.FTAssetID:
+				return tf.NewAssetFilter(chainStore.ChainStore)
 			}
 			return nil
 		},
@@ -157,6 +226,14 @@ func main() {
 	defer server.Stop()
 	server.Start()
 
+	// The orphan transactions accepted by the pool are relayed like the
+	// transactions received from peers.
+	relayCfg.Relay = func(tx *types.Transaction) {
+		hash := tx.Hash()
+		server.RelayInventory(msg.NewInvVect(msg.InvTypeTx, &hash), tx)
+	}
+	txPool.Start()
+
 	eladlog.Info("4. --Initialize pow service")
 	powCfg := pow.Config{
 		ChainParams:               activeNetParams,
@@ -164,7 +241,7 @@ func main() {
 		MinerInfo:                 cfg.MinerInfo,
 		Server:                    server,
 		Chain:                     chain,
-		TxMemPool:                 txPool,
+		TxMemPool:                 txPool.TxPool,
 		TxFeeHelper:               txFeeHelper.FeeHelper,
 		Validator:                 txValidator,
 		CreateCoinBaseTx:          pow.CreateCoinBaseTx,
@@ -178,26 +255,58 @@ func main() {
 		go powService.Start()
 	}
 
+	selector := coinselect.New(&coinselect.Config{
+		Store:       chainStore,
+		ChainParams: activeNetParams,
+		Policy:      relayCfg.Policy,
+	})
+
+	var w *wallet.Wallet
+	if cfg.EnableWallet {
+		walletFile := cfg.WalletFile
+		if len(walletFile) == 0 {
+			walletFile = filepath.Join(DataPath, DataDir, WalletFile)
+		}
+		w, err = wallet.Open(&wallet.Config{
+			Path:        walletFile,
+			Store:       chainStore,
+			TxPool:      txPool,
+			ChainParams: activeNetParams,
+			Selector:    selector,
+		})
+		if err != nil {
+			eladlog.Fatalf("open wallet failed, %s", err)
+			os.Exit(1)
+		}
+	}
+
 	eladlog.Info("5. --Start the RPC service")
 	serviceCfg := sv.Config{Config: service.Config{
 		Server:             server,
 		Chain:              chain,
 		Store:              chainStore.ChainStore,
 		GenesisAddress:     genesisAddress,
-		TxMemPool:          txPool,
+		TxMemPool:          txPool.TxPool,
 		PowService:         powService,
 		SpvService:         spvService,
 		SetLogLevel:        setLogLevel,
 		GetBlockInfo:       service.GetBlockInfo,
-		GetTransactionInfo: sv.GetTransactionInfo,
+		GetTransactionInfo: sv.TransactionInfoFunc(sv.StorePrecision(chainStore)),
 		GetTransaction:     service.GetTransaction,
 		GetPayloadInfo:     sv.GetPayloadInfo,
 		GetPayload:         service.GetPayload,
 	},
-		Compile:  Version,
-		NodePort: cfg.NodePort,
-		RPCPort:  cfg.RPCPort,
-		Store:    chainStore,
+		Compile:     Version,
+		NodePort:    cfg.NodePort,
+		RPCPort:     cfg.RPCPort,
+		Store:       chainStore,
+		AssetParams: activeAssetParams,
+		TxPool:      txPool,
+		Validator:   dryRunValidator,
+		Diagnoser:   mp.NewDiagnoser(&dryRunCfg),
+		CFilters:    cfilters,
+		Selector:    selector,
+		Wallet:      w,
 	}
 	service := sv.NewHttpService(&serviceCfg)
 
@@ -211,9 +320,37 @@ func main() {
 		}()
 	}
 
+	if cfg.EnableREST {
+		restServer := sv.NewRESTServer(&sv.RESTConfig{
+			ServePort: cfg.RESTPort,
+			User:      cfg.RPCUser,
+			Pass:      cfg.RPCPass,
+			WhiteList: cfg.RPCWhiteList,
+		}, service)
+		defer restServer.Stop()
+		go func() {
+			if err := restServer.Start(); err != nil {
+				eladlog.Errorf("Start RESTful server failed, %s", err.Error())
+			}
+		}()
+	}
+
+	if wsServer != nil {
+		defer wsServer.Stop()
+		go func() {
+			if err := wsServer.Start(); err != nil {
+				eladlog.Errorf("Start WebSocket server failed, %s", err.Error())
+			}
+		}()
+	}
+
 	go printSyncState(chainStore.ChainStore, server)
 
 	<-interrupt.C
+
+	if err := txPool.Save(txPoolFile); err != nil {
+		eladlog.Errorf("save transaction pool failed, %s", err)
+	}
 }
 
 func newRPCServer(port uint16, service *sv.HttpService) *jsonrpc.Server {
@@ -229,11 +366,20 @@ func newRPCServer(port uint16, service *sv.HttpService) *jsonrpc.Server {
 	s.RegisterAction("getblockhash", service.GetBlockHash, "height")
 	s.RegisterAction("getconnectioncount", service.GetConnectionCount)
 	s.RegisterAction("getrawmempool", service.GetTransactionPool)
-	s.RegisterAction("getrawtransaction", service.GetRawTransaction, "txid", "verbose")
+	s.RegisterAction("getmempoolinfo", service.GetMempoolInfo)
+	s.RegisterAction("getrawtransaction", service.GetRawTransaction, "txid", "verbose", "rawunits")
 	s.RegisterAction("getneighbors", service.GetNeighbors)
 	s.RegisterAction("getnodestate", service.GetNodeState)
 	s.RegisterAction("sendrechargetransaction", service.SendRechargeToSideChainTxByHash, "txid")
 	s.RegisterAction("sendrawtransaction", service.SendRawTransaction, "data")
+	s.RegisterAction("decoderawtransaction", service.DecodeRawTransaction, "data", "rawunits")
+	s.RegisterAction("createrawtransaction", service.CreateRawTransaction, "inputs", "outputs", "locktime",
+		"fromaddresses", "changeaddress", "fee", "strategy")
+	s.RegisterAction("testmempoolaccept", service.TestMempoolAccept, "data")
+	s.RegisterAction("validaterawtransaction", service.ValidateRawTransaction, "data")
+	s.RegisterAction("getoutputsbymemo", service.GetOutputsByMemo, "memo")
+	s.RegisterAction("getblockfilter", service.GetBlockFilter, "blockhash")
+	s.RegisterAction("getfilterheaders", service.GetFilterHeaders, "startheight", "stophash")
 	s.RegisterAction("getbestblockhash", service.GetBestBlockHash)
 	s.RegisterAction("getblockcount", service.GetBlockCount)
 	s.RegisterAction("getblockbyheight", service.GetBlockByHeight, "height")
@@ -244,12 +390,20 @@ func newRPCServer(port uint16, service *sv.HttpService) *jsonrpc.Server {
 	s.RegisterAction("createauxblock", service.CreateAuxBlock, "paytoaddress")
 	s.RegisterAction("togglemining", service.ToggleMining, "mining")
 	s.RegisterAction("discretemining", service.DiscreteMining, "count")
-	s.RegisterAction("getreceivedbyaddress", service.GetReceivedByAddress, "address", "assetid")
-	s.RegisterAction("listunspent", service.ListUnspent, "addresses", "assetid")
+	s.RegisterAction("getreceivedbyaddress", service.GetReceivedByAddress, "address", "assetid", "rawunits")
+	s.RegisterAction("listunspent", service.ListUnspent, "addresses", "assetid", "rawunits",
+		"minconf", "minamount", "maxamount", "sort", "desc", "cursor", "count")
 	s.RegisterAction("getassetbyhash", service.GetAssetByHash, "hash")
-	s.RegisterAction("getassetlist", service.GetAssetList)
+	s.RegisterAction("getassetlist", service.GetAssetList, "assetid", "minconf", "cursor", "count")
+	s.RegisterAction("getassetregistrationfee", service.GetAssetRegistrationFee, "name")
 	s.RegisterAction("getillegalevidencebyheight", service.GetIllegalEvidenceByHeight, "height")
 	s.RegisterAction("checkillegalevidence", service.CheckIllegalEvidence, "evidence")
+	s.RegisterAction("gettxout", service.GetTxOut, "txid", "vout", "includemempool", "rawunits")
+	s.RegisterAction("gettxoutsetinfo", service.GetTxOutSetInfo, "rawunits")
+	s.RegisterAction("getnewaddress", service.GetNewAddress, "password")
+	s.RegisterAction("getwalletbalance", service.GetWalletBalance, "minconf", "rawunits")
+	s.RegisterAction("sendtoken", service.SendToken, "outputs", "password", "changeaddress", "fee", "strategy")
+	s.RegisterAction("listwallettransactions", service.ListWalletTransactions, "count", "skip", "rawunits")
 
 	return s
 }
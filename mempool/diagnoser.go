@@ -0,0 +1,69 @@
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA.SideChain/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+)
+
+// Violation is a rule violated by a transaction.
+type Violation struct {
+	Rule        string
+	Code        mempool.ErrorCode
+	Description string
+}
+
+// Diagnoser runs every token check of the validator on a transaction, and
+// reports all the violated rules instead of stopping at the first one.
+type Diagnoser struct {
+	validator *validator
+}
+
+// Diagnose returns all the rules violated by the transaction, the sanity
+// checks are reported before the context checks.
+func (d *Diagnoser) Diagnose(tx *types.Transaction) []Violation {
+	var violations []Violation
+	for _, check := range d.validator.sanityChecks {
+		if v := runCheck(check, tx); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+	for _, check := range d.validator.contextChecks {
+		if v := runCheck(check, tx); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+	return violations
+}
+
+// runCheck runs the check function on the transaction, a check may not expect
+// the transaction that failed other checks, so panics are reported as
+// violations too.
+func runCheck(check namedCheck, tx *types.Transaction) (violation *Violation) {
+	defer func() {
+		if r := recover(); r != nil {
+			violation = &Violation{
+				Rule:        check.name,
+				Description: fmt.Sprintf("check aborted, %v", r),
+			}
+		}
+	}()
+
+	err := check.function(tx)
+	if err == nil {
+		return nil
+	}
+	if ruleErr, ok := err.(mempool.RuleError); ok {
+		return &Violation{
+			Rule:        check.name,
+			Code:        ruleErr.ErrorCode,
+			Description: ruleErr.Description,
+		}
+	}
+	return &Violation{Rule: check.name, Description: err.Error()}
+}
+
+func NewDiagnoser(cfg *Config) *Diagnoser {
+	return &Diagnoser{validator: newValidator(cfg)}
+}
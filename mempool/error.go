@@ -0,0 +1,16 @@
+package mempool
+
+import (
+	"github.com/elastos/Elastos.ELA.SideChain/mempool"
+)
+
+// These constants are the error codes of the token node specific rules.
+const (
+	ErrTokenDust           mempool.ErrorCode = 46001
+	ErrTooManyTokenOutputs mempool.ErrorCode = 46002
+	ErrPoolFull            mempool.ErrorCode = 46003
+	ErrAddressRateLimit    mempool.ErrorCode = 46004
+	ErrAssetRateLimit      mempool.ErrorCode = 46005
+	ErrOutputMemo          mempool.ErrorCode = 46006
+	ErrPoolConflict        mempool.ErrorCode = 46007
+)
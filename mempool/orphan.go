@@ -0,0 +1,304 @@
+package mempool
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SideChain/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// Tag is an identifier of the source of orphan transactions, usually the ID
+// of the peer which relayed the transaction.
+type Tag uint64
+
+// UnknownTag is the tag of orphan transactions which source is unknown, like
+// transactions appended by the side chain pool directly.
+const UnknownTag Tag = 0
+
+// OrphanPolicy defines the limits of the orphan pool.
+type OrphanPolicy struct {
+	// MaxOrphans is the maximum number of orphan transactions.
+	MaxOrphans int
+
+	// MaxOrphansPerTag is the maximum number of orphan transactions from a
+	// same source.
+	MaxOrphansPerTag int
+
+	// MaxOrphanSize is the maximum size of an orphan transaction.
+	MaxOrphanSize int
+
+	// OrphanTTL is the duration an orphan transaction is kept.
+	OrphanTTL time.Duration
+}
+
+// maxSources is the maximum number of transaction sources kept.
+const maxSources = 10000
+
+type orphanTx struct {
+	tx         *types.Transaction
+	tag        Tag
+	expiration time.Time
+}
+
+// OrphanPool keeps the transactions which referenced transactions are unknown
+// yet, indexed by the missing outpoints, so they can be retried when their
+// parents are accepted.
+type OrphanPool struct {
+	policy OrphanPolicy
+
+	mtx       sync.Mutex
+	orphans   map[common.Uint256]*orphanTx
+	outpoints map[types.OutPoint]map[common.Uint256]*orphanTx
+	tagCounts map[Tag]int
+
+	// sources is the tag of peers which announced the transactions, so the
+	// transactions become orphans are tagged by their source.
+	sources map[common.Uint256]*txSource
+}
+
+type txSource struct {
+	tag        Tag
+	expiration time.Time
+}
+
+// AddOrphan adds the transaction into the orphan pool, missing is the
+// referenced outpoints which transactions are unknown.
+func (o *OrphanPool) AddOrphan(tx *types.Transaction, missing []types.OutPoint, tag Tag) error {
+	if len(missing) == 0 {
+		return nil
+	}
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	hash := tx.Hash()
+	if _, ok := o.orphans[hash]; ok {
+		return nil
+	}
+	if size := tx.GetSize(); o.policy.MaxOrphanSize > 0 && size > o.policy.MaxOrphanSize {
+		return fmt.Errorf("orphan transaction size %d exceeds limit %d", size, o.policy.MaxOrphanSize)
+	}
+	if max := o.policy.MaxOrphansPerTag; max > 0 && o.tagCounts[tag] >= max {
+		return fmt.Errorf("orphan transactions of tag %d exceeds limit %d", tag, max)
+	}
+
+	o.expire()
+	if max := o.policy.MaxOrphans; max > 0 && len(o.orphans) >= max {
+		// evict a random orphan to make room, map iteration is random enough.
+		for evict := range o.orphans {
+			o.removeOrphan(evict)
+			break
+		}
+	}
+
+	delete(o.sources, hash)
+	orphan := &orphanTx{
+		tx:         tx,
+		tag:        tag,
+		expiration: time.Now().Add(o.policy.OrphanTTL),
+	}
+	o.orphans[hash] = orphan
+	o.tagCounts[tag]++
+	for _, outpoint := range missing {
+		if _, ok := o.outpoints[outpoint]; !ok {
+			o.outpoints[outpoint] = make(map[common.Uint256]*orphanTx)
+		}
+		o.outpoints[outpoint][hash] = orphan
+	}
+	return nil
+}
+
+// removeOrphan removes the orphan transaction, caller must hold the lock.
+func (o *OrphanPool) removeOrphan(hash common.Uint256) {
+	orphan, ok := o.orphans[hash]
+	if !ok {
+		return
+	}
+	for _, input := range orphan.tx.Inputs {
+		orphans, ok := o.outpoints[input.Previous]
+		if !ok {
+			continue
+		}
+		delete(orphans, hash)
+		if len(orphans) == 0 {
+			delete(o.outpoints, input.Previous)
+		}
+	}
+	delete(o.orphans, hash)
+	if o.tagCounts[orphan.tag]--; o.tagCounts[orphan.tag] <= 0 {
+		delete(o.tagCounts, orphan.tag)
+	}
+}
+
+// expire removes the expired orphan transactions and sources, caller must
+// hold the lock.
+func (o *OrphanPool) expire() {
+	now := time.Now()
+	for hash, orphan := range o.orphans {
+		if now.After(orphan.expiration) {
+			o.removeOrphan(hash)
+		}
+	}
+	for hash, source := range o.sources {
+		if now.After(source.expiration) {
+			delete(o.sources, hash)
+		}
+	}
+}
+
+// SetSource records the tag of the peer which announced the transaction, the
+// transaction is tagged by it if it becomes an orphan.
+func (o *OrphanPool) SetSource(hash common.Uint256, tag Tag) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	if _, ok := o.sources[hash]; ok {
+		return
+	}
+	if len(o.sources) >= maxSources {
+		o.expire()
+		for evict := range o.sources {
+			if len(o.sources) < maxSources {
+				break
+			}
+			delete(o.sources, evict)
+		}
+	}
+	o.sources[hash] = &txSource{tag: tag, expiration: time.Now().Add(o.policy.OrphanTTL)}
+}
+
+// Source returns the tag of the peer which announced the transaction, or
+// UnknownTag if the source is unknown.
+func (o *OrphanPool) Source(hash common.Uint256) Tag {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	if source, ok := o.sources[hash]; ok {
+		return source.tag
+	}
+	return UnknownTag
+}
+
+// RemoveOrphan removes the orphan transaction with the given hash.
+func (o *OrphanPool) RemoveOrphan(hash common.Uint256) {
+	o.mtx.Lock()
+	o.removeOrphan(hash)
+	o.mtx.Unlock()
+}
+
+// RemoveOrphansByTag removes all orphan transactions from the given source.
+func (o *OrphanPool) RemoveOrphansByTag(tag Tag) {
+	o.mtx.Lock()
+	for hash, orphan := range o.orphans {
+		if orphan.tag == tag {
+			o.removeOrphan(hash)
+		}
+	}
+	o.mtx.Unlock()
+}
+
+// Ready returns the orphan transactions that any of the missing referenced
+// transactions become known, and removes the expired ones.  isKnown returns if
+// the transaction with the given hash is known, it's invoked without holding the lock of the orphan pool.
+func (o *OrphanPool) Ready(isKnown func(common.Uint256) bool) []*types.Transaction {
+	o.mtx.Lock()
+	o.expire()
+	parents := make(map[common.Uint256][]*types.Transaction)
+	for outpoint, orphans := range o.outpoints {
+		for _, orphan := range orphans {
+			parents[outpoint.TxID] = append(parents[outpoint.TxID], orphan.tx)
+		}
+	}
+	o.mtx.Unlock()
+
+	var ready []*types.Transaction
+	found := make(map[common.Uint256]bool)
+	for parent, txs := range parents {
+		if !isKnown(parent) {
+			continue
+		}
+		for _, tx := range txs {
+			if hash := tx.Hash(); !found[hash] {
+				found[hash] = true
+				ready = append(ready, tx)
+			}
+		}
+	}
+	return ready
+}
+
+// Dependants returns the orphan transactions which spend outputs of the given
+// transaction.
+func (o *OrphanPool) Dependants(hash common.Uint256) []*types.Transaction {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	var dependants []*types.Transaction
+	found := make(map[common.Uint256]bool)
+	for outpoint, orphans := range o.outpoints {
+		if !outpoint.TxID.IsEqual(hash) {
+			continue
+		}
+		for orphanHash, orphan := range orphans {
+			if !found[orphanHash] {
+				found[orphanHash] = true
+				dependants = append(dependants, orphan.tx)
+			}
+		}
+	}
+	return dependants
+}
+
+// Count returns the number of orphan transactions.
+func (o *OrphanPool) Count() int {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return len(o.orphans)
+}
+
+// processOrphans appends the orphan transactions which parents are confirmed
+// into the transaction pool, and relays the accepted ones to the peers.  The
+// referenced transactions are only looked up in the blockchain, so orphans
+// spending transactions in pool are retried once their parents are confirmed
+// in a block.  Orphans still missing parents are kept, others are removed
+// whether they are accepted or not.
+func (p *TxPool) processOrphans() {
+	orphans := p.cfg.Orphans
+	if orphans == nil {
+		return
+	}
+
+	for _, tx := range orphans.Ready(p.isConfirmedTx) {
+		err := p.TxPool.AppendToTxPool(tx)
+		if err != nil {
+			p.unreserve(tx)
+		}
+		if ruleErr, ok := err.(mempool.RuleError); ok &&
+			ruleErr.ErrorCode == mempool.ErrUnknownReferedTx {
+			continue
+		}
+		orphans.RemoveOrphan(tx.Hash())
+		if err == nil && p.cfg.Relay != nil {
+			p.cfg.Relay(tx)
+		}
+	}
+}
+
+// isConfirmedTx returns if the transaction is in the blockchain.
+func (p *TxPool) isConfirmedTx(hash common.Uint256) bool {
+	_, _, err := p.cfg.ChainStore.GetTransaction(hash)
+	return err == nil
+}
+
+func NewOrphanPool(policy OrphanPolicy) *OrphanPool {
+	return &OrphanPool{
+		policy:    policy,
+		orphans:   make(map[common.Uint256]*orphanTx),
+		outpoints: make(map[types.OutPoint]map[common.Uint256]*orphanTx),
+		tagCounts: make(map[Tag]int),
+		sources:   make(map[common.Uint256]*txSource),
+	}
+}
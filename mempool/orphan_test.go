@@ -0,0 +1,62 @@
+package mempool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func orphanTestTx(parent common.Uint256, lockTime uint32) *types.Transaction {
+	return &types.Transaction{
+		TxType:   types.TransferAsset,
+		Payload:  &types.PayloadTransferAsset{},
+		LockTime: lockTime,
+		Inputs: []*types.Input{{
+			Previous: types.OutPoint{TxID: parent},
+		}},
+	}
+}
+
+func TestOrphanTags(t *testing.T) {
+	orphans := NewOrphanPool(OrphanPolicy{MaxOrphansPerTag: 1, OrphanTTL: time.Hour})
+
+	tx1 := orphanTestTx(common.Uint256{1}, 1)
+	tx2 := orphanTestTx(common.Uint256{1}, 2)
+	tx3 := orphanTestTx(common.Uint256{1}, 3)
+	orphans.SetSource(tx1.Hash(), 1)
+	orphans.SetSource(tx2.Hash(), 2)
+	assert.Equal(t, Tag(1), orphans.Source(tx1.Hash()))
+	assert.Equal(t, UnknownTag, orphans.Source(tx3.Hash()))
+
+	// The per peer limit only applies to the orphans of a same peer.
+	for _, tx := range []*types.Transaction{tx1, tx2} {
+		missing := []types.OutPoint{tx.Inputs[0].Previous}
+		assert.NoError(t, orphans.AddOrphan(tx, missing, orphans.Source(tx.Hash())))
+	}
+	tx4 := orphanTestTx(common.Uint256{1}, 4)
+	assert.Error(t, orphans.AddOrphan(tx4, []types.OutPoint{tx4.Inputs[0].Previous}, 1))
+	assert.Equal(t, 2, orphans.Count())
+
+	orphans.RemoveOrphansByTag(1)
+	assert.Equal(t, 1, orphans.Count())
+	assert.Equal(t, []*types.Transaction{tx2}, orphans.Dependants(common.Uint256{1}))
+}
+
+func TestOrphanDependants(t *testing.T) {
+	orphans := NewOrphanPool(OrphanPolicy{OrphanTTL: time.Hour})
+
+	parent := orphanTestTx(common.Uint256{1}, 1)
+	child := orphanTestTx(parent.Hash(), 2)
+	assert.NoError(t, orphans.AddOrphan(parent, []types.OutPoint{parent.Inputs[0].Previous}, UnknownTag))
+	assert.NoError(t, orphans.AddOrphan(child, []types.OutPoint{child.Inputs[0].Previous}, UnknownTag))
+
+	ready := orphans.Ready(func(hash common.Uint256) bool {
+		return hash.IsEqual(common.Uint256{1})
+	})
+	assert.Equal(t, []*types.Transaction{parent}, ready)
+	assert.Equal(t, []*types.Transaction{child}, orphans.Dependants(parent.Hash()))
+	assert.Empty(t, orphans.Dependants(child.Hash()))
+}
@@ -0,0 +1,153 @@
+package mempool
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+const (
+	// poolFileVersion is the version of the persisted transaction pool file.
+	poolFileVersion = 1
+
+	// maxPoolFileTxs is the maximum number of transactions can be loaded from
+	// the persisted transaction pool file.
+	maxPoolFileTxs = 1000000
+)
+
+// Save writes the transactions in pool to the given file, so they can be
+// loaded after the node restarted.
+func (p *TxPool) Save(path string) error {
+	txs := p.GetTxsInPool()
+
+	p.mtx.Lock()
+	firstSeen := make(map[common.Uint256]time.Time, len(txs))
+	for hash := range txs {
+		if entry, ok := p.entries[hash]; ok {
+			firstSeen[hash] = entry.firstSeen
+		}
+	}
+	p.mtx.Unlock()
+
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	if err := serializePool(w, txs, firstSeen); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Load reads transactions from the given file and append them into pool
+// again.  Transactions first seen before the expiry duration are dropped, and
+// others must pass the sanity and context checks again.  It returns the number
+// of transactions appended into pool.
+func (p *TxPool) Load(path string, expiry time.Duration) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	version, err := common.ReadUint32(r)
+	if err != nil {
+		return 0, err
+	}
+	if version != poolFileVersion {
+		return 0, fmt.Errorf("unknown transaction pool file version %d", version)
+	}
+	count, err := common.ReadVarUint(r, maxPoolFileTxs)
+	if err != nil {
+		return 0, err
+	}
+
+	loaded := 0
+	now := time.Now()
+	for i := uint64(0); i < count; i++ {
+		seen, err := common.ReadUint64(r)
+		if err != nil {
+			return loaded, err
+		}
+		tx := new(types.Transaction)
+		if err := tx.Deserialize(r); err != nil {
+			return loaded, err
+		}
+
+		firstSeen := time.Unix(int64(seen), 0)
+		if expiry > 0 && now.Sub(firstSeen) > expiry {
+			continue
+		}
+		if err := p.revalidate(tx); err != nil {
+			continue
+		}
+		if err := p.AppendToTxPool(tx); err != nil {
+			continue
+		}
+
+		p.mtx.Lock()
+		if entry, ok := p.entries[tx.Hash()]; ok {
+			entry.firstSeen = firstSeen
+		}
+		p.mtx.Unlock()
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// revalidate checks the transaction loaded from file against the current
+// chain state.
+func (p *TxPool) revalidate(tx *types.Transaction) error {
+	if p.cfg.Validator == nil {
+		return errors.New("no validator to check transaction")
+	}
+	if err := p.cfg.Validator.CheckTransactionSanity(tx); err != nil {
+		return err
+	}
+	return p.cfg.Validator.CheckTransactionContext(tx)
+}
+
+func serializePool(w io.Writer, txs map[common.Uint256]*types.Transaction,
+	firstSeen map[common.Uint256]time.Time) error {
+	if err := common.WriteUint32(w, poolFileVersion); err != nil {
+		return err
+	}
+	if err := common.WriteVarUint(w, uint64(len(txs))); err != nil {
+		return err
+	}
+	now := time.Now()
+	for hash, tx := range txs {
+		seen, ok := firstSeen[hash]
+		if !ok || seen.IsZero() {
+			seen = now
+		}
+		if err := common.WriteUint64(w, uint64(seen.Unix())); err != nil {
+			return err
+		}
+		if err := tx.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
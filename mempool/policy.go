@@ -0,0 +1,66 @@
+package mempool
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+)
+
+// Policy defines the relay policies of the transaction pool.  They are the
+// standardness rules of transactions waiting to be packed, transactions in
+// blocks are not checked against them.
+type Policy struct {
+	// TokenDustUnits is the minimum value of a token output, counted in the
+	// smallest unit of the asset precision.  It is not applied to assets
+	// which TokenDustUnits smallest units reach one whole token.
+	TokenDustUnits int64
+
+	// MaxTokenOutputsPerAsset is the maximum number of outputs of one token
+	// asset in a transaction.
+	MaxTokenOutputsPerAsset int
+
+	// MaxPoolSize is the maximum total size in bytes of transactions in pool.
+	MaxPoolSize int
+
+	// MaxPoolCount is the maximum number of transactions in pool.
+	MaxPoolCount int
+
+	// FeeRateHalfLife is the duration that the minimum fee rate raised by
+	// evictions decays by half.
+	FeeRateHalfLife time.Duration
+
+	// MaxTxsPerAddress is the maximum number of transactions in pool that
+	// spend outputs of a same address.
+	MaxTxsPerAddress int
+
+	// MaxBytesPerAddress is the maximum total size of transactions in pool
+	// that spend outputs of a same address.
+	MaxBytesPerAddress int
+
+	// MaxTxsPerAsset is the maximum number of transactions in pool that
+	// transfer a same token asset.
+	MaxTxsPerAsset int
+
+	// MaxBytesPerAsset is the maximum total size of transactions in pool that
+	// transfer a same token asset.
+	MaxBytesPerAsset int
+}
+
+// DustThreshold returns the minimum value of a token output of the asset with
+// the given precision.  An asset which TokenDustUnits smallest units reach a
+// whole token is too coarse to have dust, the threshold is the smallest unit,
+// so any precise value is accepted.
+func (p *Policy) DustThreshold(precision byte) *core.TokenAmount {
+	if precision > core.TokenPrecision {
+		precision = core.TokenPrecision
+	}
+
+	unit := new(big.Int).Exp(big.NewInt(10),
+		big.NewInt(int64(core.TokenPrecision-precision)), nil)
+	threshold := new(big.Int).Mul(unit, big.NewInt(p.TokenDustUnits))
+	if threshold.Cmp(core.TokenAmountFromUnits(1).Int()) >= 0 {
+		return core.NewTokenAmount(unit)
+	}
+	return core.NewTokenAmount(threshold)
+}
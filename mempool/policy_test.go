@@ -0,0 +1,43 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDustThreshold(t *testing.T) {
+	policy := &Policy{TokenDustUnits: 1000}
+	amount := func(str string, precision byte) *core.TokenAmount {
+		a, err := core.ParseTokenAmount(str, precision)
+		assert.NoError(t, err)
+		return a
+	}
+
+	// The threshold of coarse assets is the smallest unit, so sub-token
+	// values are not dust.
+	for precision, unit := range map[byte]string{
+		0: "1",
+		1: "0.1",
+		2: "0.01",
+		3: "0.001",
+	} {
+		threshold := policy.DustThreshold(precision)
+		assert.Equal(t, 0, threshold.Cmp(amount(unit, precision)), "precision %d", precision)
+		assert.True(t, threshold.Cmp(core.TokenAmountFromUnits(1)) <= 0)
+	}
+	assert.True(t, amount("0.5", 1).Cmp(policy.DustThreshold(1)) >= 0)
+	assert.True(t, amount("0.07", 2).Cmp(policy.DustThreshold(2)) >= 0)
+
+	// The threshold is TokenDustUnits smallest units of finer assets.
+	for precision, threshold := range map[byte]string{
+		4:  "0.1",
+		8:  "0.00001",
+		18: "0.000000000000001",
+	} {
+		assert.Equal(t, 0, policy.DustThreshold(precision).Cmp(amount(threshold, precision)),
+			"precision %d", precision)
+	}
+}
@@ -0,0 +1,149 @@
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA.SideChain/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+const CheckPoolRateLimit = "checkpoolratelimit"
+
+// PoolUsage is the number and total size of transactions in pool.
+type PoolUsage struct {
+	Count int
+	Size  int
+}
+
+func (u *PoolUsage) add(size int) {
+	u.Count++
+	u.Size += size
+}
+
+func (u *PoolUsage) remove(size int) {
+	u.Count--
+	u.Size -= size
+}
+
+// exceeds returns if the usage exceeds the given limits after adding a
+// transaction with the given size, zero limit means no limit.
+func (u PoolUsage) exceeds(size, maxCount, maxSize int) bool {
+	if maxCount > 0 && u.Count+1 > maxCount {
+		return true
+	}
+	if maxSize > 0 && u.Size+size > maxSize {
+		return true
+	}
+	return false
+}
+
+// entrySources returns the program hashes of the referenced outputs and the
+// token asset IDs of the transaction.
+func (p *TxPool) entrySources(tx *types.Transaction) ([]common.Uint168, []common.Uint256) {
+	var programHashes []common.Uint168
+	seenHashes := make(map[common.Uint168]bool)
+	for _, input := range tx.Inputs {
+		referTxn, _, err := p.cfg.ChainStore.GetTransaction(input.Previous.TxID)
+		if err != nil || int(input.Previous.Index) >= len(referTxn.Outputs) {
+			continue
+		}
+		programHash := referTxn.Outputs[input.Previous.Index].ProgramHash
+		if !seenHashes[programHash] {
+			seenHashes[programHash] = true
+			programHashes = append(programHashes, programHash)
+		}
+	}
+
+	var assetIDs []common.Uint256
+	seenAssets := make(map[common.Uint256]bool)
+	for _, output := range tx.Outputs {
+		if output.AssetID.IsEqual(p.cfg.ChainParams.ElaAssetId) {
+			continue
+		}
+		if !seenAssets[output.AssetID] {
+			seenAssets[output.AssetID] = true
+			assetIDs = append(assetIDs, output.AssetID)
+		}
+	}
+	return programHashes, assetIDs
+}
+
+// addUsage adds the entry into the usage counters, caller must hold the lock.
+func (p *TxPool) addUsage(entry *txEntry) {
+	for _, programHash := range entry.programHashes {
+		usage, ok := p.addressUsage[programHash]
+		if !ok {
+			usage = new(PoolUsage)
+			p.addressUsage[programHash] = usage
+		}
+		usage.add(entry.size)
+	}
+	for _, assetID := range entry.assetIDs {
+		usage, ok := p.assetUsage[assetID]
+		if !ok {
+			usage = new(PoolUsage)
+			p.assetUsage[assetID] = usage
+		}
+		usage.add(entry.size)
+	}
+}
+
+// removeUsage removes the entry from the usage counters, caller must hold the
+// lock.
+func (p *TxPool) removeUsage(entry *txEntry) {
+	for _, programHash := range entry.programHashes {
+		if usage, ok := p.addressUsage[programHash]; ok {
+			if usage.remove(entry.size); usage.Count <= 0 {
+				delete(p.addressUsage, programHash)
+			}
+		}
+	}
+	for _, assetID := range entry.assetIDs {
+		if usage, ok := p.assetUsage[assetID]; ok {
+			if usage.remove(entry.size); usage.Count <= 0 {
+				delete(p.assetUsage, assetID)
+			}
+		}
+	}
+}
+
+// checkPoolRateLimit rejects the transaction if the pending transactions of
+// any of it's input addresses or token assets exceeds the limits.
+func (p *TxPool) checkPoolRateLimit(tx *types.Transaction) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if _, ok := p.entries[tx.Hash()]; ok {
+		return nil
+	}
+	programHashes, assetIDs := p.entrySources(tx)
+	return p.rateLimit(tx.GetSize(), programHashes, assetIDs)
+}
+
+// rateLimit returns the error if adding a transaction of the given size and
+// sources exceeds the limits of pending transactions, caller must hold the
+// lock.
+func (p *TxPool) rateLimit(size int, programHashes []common.Uint168, assetIDs []common.Uint256) error {
+	policy := p.cfg.Policy
+	if policy == nil {
+		return nil
+	}
+	for _, programHash := range programHashes {
+		usage, ok := p.addressUsage[programHash]
+		if ok && usage.exceeds(size, policy.MaxTxsPerAddress, policy.MaxBytesPerAddress) {
+			address, _ := programHash.ToAddress()
+			desc := fmt.Sprintf("[checkPoolRateLimit] pending transactions of address %s exceeds the limit", address)
+			return mempool.RuleError{ErrorCode: ErrAddressRateLimit, Description: desc}
+		}
+	}
+	for _, assetID := range assetIDs {
+		usage, ok := p.assetUsage[assetID]
+		if ok && usage.exceeds(size, policy.MaxTxsPerAsset, policy.MaxBytesPerAsset) {
+			desc := fmt.Sprintf("[checkPoolRateLimit] pending transactions of asset %s exceeds the limit",
+				common.BytesToHexString(common.BytesReverse(assetID.Bytes())))
+			return mempool.RuleError{ErrorCode: ErrAssetRateLimit, Description: desc}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain/config"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	pool := NewTxPool(&Config{
+		ChainParams: &config.Params{},
+		Policy:      &Policy{MaxTxsPerAddress: 2, MaxBytesPerAsset: 1500},
+	}, nil)
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	address := []common.Uint168{{0x21, 0x01}}
+	asset := []common.Uint256{{0x01}}
+	entry := testEntry(1, 1000)
+	entry.programHashes = address
+	pool.addEntry(entry)
+	assert.NoError(t, pool.rateLimit(100, address, nil))
+
+	entry = testEntry(2, 1000)
+	entry.programHashes = address
+	entry.assetIDs = asset
+	pool.addEntry(entry)
+	assert.Error(t, pool.rateLimit(100, address, nil))
+	assert.NoError(t, pool.rateLimit(500, nil, asset))
+	assert.Error(t, pool.rateLimit(501, nil, asset))
+
+	// The usage is released when the transactions leave the pool.
+	pool.removeEntry(entry.tx.Hash())
+	assert.NoError(t, pool.rateLimit(100, address, nil))
+	assert.NoError(t, pool.rateLimit(1500, nil, asset))
+}
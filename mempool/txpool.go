@@ -1,11 +1,28 @@
 package mempool
 
 import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
+
 	"github.com/elastos/Elastos.ELA.SideChain/blockchain"
 	"github.com/elastos/Elastos.ELA.SideChain/config"
 	"github.com/elastos/Elastos.ELA.SideChain/mempool"
 	"github.com/elastos/Elastos.ELA.SideChain/spv"
 	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+const (
+	// trackInterval is the interval to track the transactions in pool.
+	trackInterval = 10 * time.Second
+
+	CheckPoolCapacity = "checkpoolcapacity"
+	CheckPoolReserve  = "checkpoolreserve"
 )
 
 type GetReference func(*types.Transaction) (map[*types.Input]*types.Output, error)
@@ -16,4 +33,537 @@ type Config struct {
 	SpvService  *spv.Service
 	Validator   *mempool.Validator
 	FeeHelper   *FeeHelper
+	AssetParams *params.AssetParams
+	Policy      *Policy
+	Orphans     *OrphanPool
+
+	// Relay announces the transactions appended into the pool out of the
+	// peer messages, such as the orphan transactions which parents are
+	// confirmed, to the connected peers.
+	Relay func(*types.Transaction)
+}
+
+// txEntry is a transaction tracked in the pool.
+type txEntry struct {
+	tx        *types.Transaction
+	firstSeen time.Time
+	size      int
+	fee       common.Fixed64
+
+	programHashes []common.Uint168
+	assetIDs      []common.Uint256
+
+	// seq is the order the entry is added into the snapshot, and notified is
+	// set once the listeners are notified of the transaction.
+	seq      uint64
+	notified bool
+}
+
+// PoolInfo is the usage and limits of the transaction pool.
+type PoolInfo struct {
+	Count      int
+	Size       int
+	MaxCount   int
+	MaxSize    int
+	MinFeeRate common.Fixed64
+	Addresses  map[common.Uint168]PoolUsage
+	Assets     map[common.Uint256]PoolUsage
+}
+
+// TxPool extends the side chain transaction pool with the token node features.
+//
+// The callbacks of the validator are invoked while the side chain transaction
+// pool is locked, so the TxPool keeps its own snapshot of transactions in pool
+// for the relay policy checks, and evicts transactions out of the callbacks.
+// Every transaction accepted by the relay validator is reserved into the
+// snapshot by the last check, so the snapshot is kept up to date whether the
+// transaction is appended through the TxPool or the side chain pool directly,
+// such as transactions relayed by peers.
+type TxPool struct {
+	*mempool.TxPool
+	cfg *Config
+
+	mtx        sync.Mutex
+	entries    map[common.Uint256]*txEntry
+	spends     map[types.OutPoint]common.Uint256
+	totalSize  int
+	minFeeRate float64
+	lastDecay  time.Time
+	seq        uint64
+
+	// evictions is the transactions evicted from the snapshot but not
+	// removed from the side chain pool yet.
+	evictions map[common.Uint256]*types.Transaction
+
+	addressUsage map[common.Uint168]*PoolUsage
+	assetUsage   map[common.Uint256]*PoolUsage
+
+	trackMtx  sync.Mutex
+	trackNow  chan struct{}
+	listeners []func(*types.Transaction)
+}
+
+// feeRate returns the fee per KB of the given fee and size.
+func feeRate(fee common.Fixed64, size int) float64 {
+	if size == 0 {
+		return 0
+	}
+	return float64(fee) * 1000 / float64(size)
+}
+
+func (p *TxPool) newEntry(tx *types.Transaction, firstSeen time.Time) *txEntry {
+	fee, err := p.cfg.FeeHelper.GetTxFee(tx, p.cfg.ChainParams.ElaAssetId)
+	if err != nil {
+		fee = 0
+	}
+	programHashes, assetIDs := p.entrySources(tx)
+	return &txEntry{
+		tx:            tx,
+		firstSeen:     firstSeen,
+		size:          tx.GetSize(),
+		fee:           fee,
+		programHashes: programHashes,
+		assetIDs:      assetIDs,
+	}
+}
+
+// addEntry adds the transaction into the snapshot, caller must hold the lock.
+func (p *TxPool) addEntry(entry *txEntry) {
+	hash := entry.tx.Hash()
+	if _, ok := p.entries[hash]; ok {
+		return
+	}
+	p.seq++
+	entry.seq = p.seq
+	p.entries[hash] = entry
+	for _, input := range entry.tx.Inputs {
+		p.spends[input.Previous] = hash
+	}
+	p.totalSize += entry.size
+	p.addUsage(entry)
+}
+
+// removeEntry removes the transaction from the snapshot, caller must hold the
+// lock.
+func (p *TxPool) removeEntry(hash common.Uint256) {
+	entry, ok := p.entries[hash]
+	if !ok {
+		return
+	}
+	delete(p.entries, hash)
+	for _, input := range entry.tx.Inputs {
+		if spender, ok := p.spends[input.Previous]; ok && spender.IsEqual(hash) {
+			delete(p.spends, input.Previous)
+		}
+	}
+	p.totalSize -= entry.size
+	p.removeUsage(entry)
+}
+
+// conflict returns the transaction in the snapshot which spends any of the
+// inputs of the given transaction, caller must hold the lock.
+func (p *TxPool) conflict(tx *types.Transaction) (common.Uint256, bool) {
+	hash := tx.Hash()
+	for _, input := range tx.Inputs {
+		if spender, ok := p.spends[input.Previous]; ok && !spender.IsEqual(hash) {
+			return spender, true
+		}
+	}
+	return common.Uint256{}, false
+}
+
+// Conflict returns the transaction in pool which spends any of the inputs of
+// the given transaction.
+func (p *TxPool) Conflict(tx *types.Transaction) (common.Uint256, bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.conflict(tx)
+}
+
+// track refreshes the snapshot of transactions in pool, removes the evicted
+// transactions from pool, notifies the listeners of new transactions and
+// retries the orphans which parents are accepted.
+func (p *TxPool) track() {
+	p.trackMtx.Lock()
+	defer p.trackMtx.Unlock()
+
+	p.mtx.Lock()
+	evictions := p.evictions
+	p.evictions = make(map[common.Uint256]*types.Transaction)
+	p.mtx.Unlock()
+	for _, tx := range evictions {
+		p.RemoveTransaction(tx)
+	}
+
+	p.mtx.Lock()
+	seq := p.seq
+	p.mtx.Unlock()
+	txs := p.GetTxsInPool()
+	now := time.Now()
+
+	var added []*types.Transaction
+	p.mtx.Lock()
+	for hash, tx := range txs {
+		if _, ok := p.evictions[hash]; ok {
+			continue
+		}
+		entry, ok := p.entries[hash]
+		if !ok {
+			entry = p.newEntry(tx, now)
+			p.addEntry(entry)
+		}
+		if !entry.notified {
+			entry.notified = true
+			added = append(added, tx)
+		}
+	}
+	// Entries reserved after the pool is read may be appended already.
+	for hash, entry := range p.entries {
+		if _, ok := txs[hash]; !ok && entry.seq <= seq {
+			p.removeEntry(hash)
+		}
+	}
+	p.evict(p.trim())
+	evictions = p.evictions
+	p.evictions = make(map[common.Uint256]*types.Transaction)
+	p.mtx.Unlock()
+
+	for _, tx := range evictions {
+		p.RemoveTransaction(tx)
+	}
+
+	for _, tx := range added {
+		for _, listener := range p.listeners {
+			listener(tx)
+		}
+	}
+
+	p.processOrphans()
+}
+
+// signal requests the track handler to track the pool as soon as possible.
+func (p *TxPool) signal() {
+	select {
+	case p.trackNow <- struct{}{}:
+	default:
+	}
+}
+
+// exceedsLimits returns if the snapshot exceeds the pool limits after adding
+// count transactions of the given size, caller must hold the lock.
+func (p *TxPool) exceedsLimits(count, size int) bool {
+	policy := p.cfg.Policy
+	if policy == nil {
+		return false
+	}
+	if policy.MaxPoolCount > 0 && len(p.entries)+count > policy.MaxPoolCount {
+		return true
+	}
+	if policy.MaxPoolSize > 0 && p.totalSize+size > policy.MaxPoolSize {
+		return true
+	}
+	return false
+}
+
+// packages returns the transaction packages in the snapshot sorted by fee rate
+// in ascending order.  A package is a transaction with all it's descendants in
+// the pool, caller must hold the lock.
+func (p *TxPool) packages() [][]*txEntry {
+	spenders := make(map[common.Uint256][]*txEntry)
+	for _, entry := range p.entries {
+		for _, input := range entry.tx.Inputs {
+			if _, ok := p.entries[input.Previous.TxID]; ok {
+				spenders[input.Previous.TxID] = append(spenders[input.Previous.TxID], entry)
+			}
+		}
+	}
+
+	var packages [][]*txEntry
+	for hash, entry := range p.entries {
+		pkg := []*txEntry{entry}
+		visited := map[common.Uint256]bool{hash: true}
+		for i := 0; i < len(pkg); i++ {
+			for _, spender := range spenders[pkg[i].tx.Hash()] {
+				spenderHash := spender.tx.Hash()
+				if !visited[spenderHash] {
+					visited[spenderHash] = true
+					pkg = append(pkg, spender)
+				}
+			}
+		}
+		packages = append(packages, pkg)
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packageFeeRate(packages[i]) < packageFeeRate(packages[j])
+	})
+	return packages
+}
+
+func packageFeeRate(pkg []*txEntry) float64 {
+	var fee common.Fixed64
+	var size int
+	for _, entry := range pkg {
+		fee += entry.fee
+		size += entry.size
+	}
+	return feeRate(fee, size)
+}
+
+// trim removes the lowest fee rate packages from the snapshot until the pool
+// is within it's limits, and returns the removed entries.  Caller must hold the
+// lock.
+func (p *TxPool) trim() []*txEntry {
+	if !p.exceedsLimits(0, 0) {
+		return nil
+	}
+
+	var evicted []*txEntry
+	for _, pkg := range p.packages() {
+		if !p.exceedsLimits(0, 0) {
+			break
+		}
+		if _, ok := p.entries[pkg[0].tx.Hash()]; !ok {
+			continue
+		}
+		p.raiseMinFeeRate(pkg)
+		for _, entry := range pkg {
+			if _, ok := p.entries[entry.tx.Hash()]; !ok {
+				continue
+			}
+			p.removeEntry(entry.tx.Hash())
+			evicted = append(evicted, entry)
+		}
+	}
+	return evicted
+}
+
+// evict marks the entries removed from the snapshot to be removed from pool,
+// caller must hold the lock.
+func (p *TxPool) evict(entries []*txEntry) {
+	for _, entry := range entries {
+		p.evictions[entry.tx.Hash()] = entry.tx
+	}
+}
+
+// raiseMinFeeRate raises the minimum fee rate so a transaction replacing the
+// evicted package pays at least the minimum transaction fee more than it,
+// caller must hold the lock.
+func (p *TxPool) raiseMinFeeRate(pkg []*txEntry) {
+	var fee common.Fixed64
+	var size int
+	for _, entry := range pkg {
+		fee += entry.fee
+		size += entry.size
+	}
+	rate := feeRate(fee+common.Fixed64(p.cfg.ChainParams.MinTransactionFee), size)
+	if current := p.decayedMinFeeRate(); rate < current {
+		rate = current
+	}
+	p.minFeeRate = rate
+	p.lastDecay = time.Now()
+}
+
+// decayedMinFeeRate returns the minimum fee rate that decays by half every
+// FeeRateHalfLife since the last eviction, caller must hold the lock.
+func (p *TxPool) decayedMinFeeRate() float64 {
+	if p.minFeeRate == 0 || p.cfg.Policy == nil || p.cfg.Policy.FeeRateHalfLife <= 0 {
+		return p.minFeeRate
+	}
+
+	elapsed := time.Since(p.lastDecay)
+	rate := p.minFeeRate * math.Pow(0.5, float64(elapsed)/float64(p.cfg.Policy.FeeRateHalfLife))
+	if rate < feeRate(common.Fixed64(p.cfg.ChainParams.MinTransactionFee), 1000)/2 {
+		rate = 0
+	}
+	p.minFeeRate = rate
+	p.lastDecay = time.Now()
+	return rate
+}
+
+// checkPoolCapacity rejects the transaction which fee rate is lower than the
+// minimum fee rate, or too low to replace transactions in the full pool.
+func (p *TxPool) checkPoolCapacity(tx *types.Transaction) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if _, ok := p.entries[tx.Hash()]; ok {
+		return nil
+	}
+
+	entry := p.newEntry(tx, time.Now())
+	rate := feeRate(entry.fee, entry.size)
+	if minRate := p.decayedMinFeeRate(); rate < minRate {
+		desc := fmt.Sprintf("[checkPoolCapacity] fee rate %.0f is lower than the minimum %.0f", rate, minRate)
+		return mempool.RuleError{ErrorCode: ErrPoolFull, Description: desc}
+	}
+
+	if p.exceedsLimits(1, entry.size) {
+		packages := p.packages()
+		if len(packages) == 0 || rate <= packageFeeRate(packages[0]) {
+			desc := fmt.Sprintf("[checkPoolCapacity] transaction pool is full, fee rate %.0f is too low", rate)
+			return mempool.RuleError{ErrorCode: ErrPoolFull, Description: desc}
+		}
+	}
+
+	return nil
+}
+
+// reserveEntry adds the transaction accepted by the other checks into the
+// snapshot and evicts the lowest fee rate packages if the pool exceeds it's
+// limits, so the next transaction is checked against the live pool.  It is the
+// last check of the relay validator, which is invoked under the side chain pool
+// lock.  The transaction is rejected if it double spends a transaction in pool,
+// exceeds the rate limits of it's sources or it would be evicted itself.
+func (p *TxPool) reserveEntry(tx *types.Transaction) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	hash := tx.Hash()
+	if _, ok := p.entries[hash]; ok {
+		return nil
+	}
+	if spender, ok := p.conflict(tx); ok {
+		desc := fmt.Sprintf("[checkPoolReserve] transaction double spends transaction %s in pool",
+			common.BytesToHexString(common.BytesReverse(spender.Bytes())))
+		return mempool.RuleError{ErrorCode: ErrPoolConflict, Description: desc}
+	}
+
+	entry := p.newEntry(tx, time.Now())
+	if err := p.rateLimit(entry.size, entry.programHashes, entry.assetIDs); err != nil {
+		return err
+	}
+
+	minFeeRate, lastDecay := p.minFeeRate, p.lastDecay
+	p.addEntry(entry)
+	evicted := p.trim()
+	for _, e := range evicted {
+		if e != entry {
+			continue
+		}
+		for _, e := range evicted {
+			if e != entry {
+				p.addEntry(e)
+			}
+		}
+		p.minFeeRate, p.lastDecay = minFeeRate, lastDecay
+		desc := "[checkPoolReserve] transaction pool is full, the transaction package fee rate is too low"
+		return mempool.RuleError{ErrorCode: ErrPoolFull, Description: desc}
+	}
+	p.evict(evicted)
+	p.signal()
+	return nil
+}
+
+// unreserve removes the transaction reserved by the checks from the snapshot
+// if it is not appended into pool.
+func (p *TxPool) unreserve(tx *types.Transaction) {
+	hash := tx.Hash()
+	if p.GetTransaction(hash) != nil {
+		return
+	}
+	p.mtx.Lock()
+	if entry, ok := p.entries[hash]; ok && !entry.notified {
+		p.removeEntry(hash)
+	}
+	p.mtx.Unlock()
+}
+
+// AppendToTxPool appends the transaction into pool, and evicts the lowest fee
+// rate transactions if the pool exceeds its limits.
+func (p *TxPool) AppendToTxPool(tx *types.Transaction) error {
+	if err := p.TxPool.AppendToTxPool(tx); err != nil {
+		p.unreserve(tx)
+		return err
+	}
+	p.track()
+	return nil
+}
+
+// Info returns the usage and limits of the pool.
+func (p *TxPool) Info() PoolInfo {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	info := PoolInfo{
+		Count:      len(p.entries),
+		Size:       p.totalSize,
+		MinFeeRate: common.Fixed64(p.decayedMinFeeRate()),
+	}
+	if p.cfg.Policy != nil {
+		info.MaxCount = p.cfg.Policy.MaxPoolCount
+		info.MaxSize = p.cfg.Policy.MaxPoolSize
+	}
+	info.Addresses = make(map[common.Uint168]PoolUsage, len(p.addressUsage))
+	for programHash, usage := range p.addressUsage {
+		info.Addresses[programHash] = *usage
+	}
+	info.Assets = make(map[common.Uint256]PoolUsage, len(p.assetUsage))
+	for assetID, usage := range p.assetUsage {
+		info.Assets[assetID] = *usage
+	}
+	return info
+}
+
+// AddTxListener adds the listener to be notified of transactions appended into
+// pool, it must be called before the pool starts.  Accepting a transaction
+// signals the track handler, which waits for the append to complete, so the
+// listeners are notified right after the transaction is accepted rather than
+// on the next track interval.
+func (p *TxPool) AddTxListener(listener func(*types.Transaction)) {
+	p.listeners = append(p.listeners, listener)
+}
+
+func (p *TxPool) trackHandler() {
+	ticker := time.NewTicker(trackInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.trackNow:
+		}
+		p.track()
+	}
+}
+
+// Start starts tracking transactions in the pool.
+func (p *TxPool) Start() {
+	go p.trackHandler()
+}
+
+// OnBlockPersisted tracks the pool after the transactions of the block are
+// removed from pool.
+func (p *TxPool) OnBlockPersisted(b *types.Block) {
+	p.signal()
+}
+
+// OnBlockRolledBack tracks the pool after a block is rolled back.
+func (p *TxPool) OnBlockRolledBack(b *types.Block) {
+	p.signal()
+}
+
+// RegisterChecks registers the pool policy checks into the given validator.
+func (p *TxPool) RegisterChecks(validator *mempool.Validator) {
+	if p.cfg.Policy != nil {
+		validator.RegisterContextFunc(CheckPoolCapacity, p.checkPoolCapacity)
+		validator.RegisterContextFunc(CheckPoolRateLimit, p.checkPoolRateLimit)
+	}
+}
+
+func NewTxPool(cfg *Config, txPool *mempool.TxPool) *TxPool {
+	pool := &TxPool{
+		TxPool:       txPool,
+		cfg:          cfg,
+		entries:      make(map[common.Uint256]*txEntry),
+		spends:       make(map[types.OutPoint]common.Uint256),
+		evictions:    make(map[common.Uint256]*types.Transaction),
+		addressUsage: make(map[common.Uint168]*PoolUsage),
+		assetUsage:   make(map[common.Uint256]*PoolUsage),
+		trackNow:     make(chan struct{}, 1),
+	}
+	if cfg.Validator != nil {
+		pool.RegisterChecks(cfg.Validator)
+		cfg.Validator.RegisterContextFunc(CheckPoolReserve, pool.reserveEntry)
+	}
+	return pool
 }
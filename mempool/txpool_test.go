@@ -0,0 +1,73 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain/config"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEntry(index byte, fee common.Fixed64) *txEntry {
+	tx := &types.Transaction{
+		TxType:  types.TransferAsset,
+		Payload: &types.PayloadTransferAsset{},
+		Inputs: []*types.Input{{
+			Previous: types.OutPoint{TxID: common.Uint256{index}},
+		}},
+	}
+	return &txEntry{tx: tx, size: 1000, fee: fee}
+}
+
+func TestTrim(t *testing.T) {
+	pool := NewTxPool(&Config{
+		ChainParams: &config.Params{MinTransactionFee: 100},
+		Policy:      &Policy{MaxPoolCount: 3},
+	}, nil)
+
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	for i := byte(1); i <= 3; i++ {
+		pool.addEntry(testEntry(i, common.Fixed64(i)*1000))
+	}
+	assert.Empty(t, pool.trim())
+	assert.Equal(t, 3, len(pool.entries))
+
+	// Only the lowest fee rate transaction is evicted when the pool exceeds
+	// the limit by one.
+	pool.addEntry(testEntry(4, 4000))
+	evicted := pool.trim()
+	if assert.Equal(t, 1, len(evicted)) {
+		assert.Equal(t, common.Fixed64(1000), evicted[0].fee)
+	}
+	assert.Equal(t, 3, len(pool.entries))
+	_, ok := pool.spends[types.OutPoint{TxID: common.Uint256{1}}]
+	assert.False(t, ok)
+
+	// The minimum fee rate is the fee rate of the evicted transaction with
+	// the minimum transaction fee added, both per KB.
+	assert.Equal(t, float64(1100), pool.minFeeRate)
+}
+
+func TestConflict(t *testing.T) {
+	pool := NewTxPool(&Config{ChainParams: &config.Params{}}, nil)
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	entry := testEntry(1, 1000)
+	pool.addEntry(entry)
+	_, ok := pool.conflict(entry.tx)
+	assert.False(t, ok)
+
+	tx := testEntry(1, 2000).tx
+	tx.LockTime = 1
+	spender, ok := pool.conflict(tx)
+	assert.True(t, ok)
+	assert.Equal(t, entry.tx.Hash(), spender)
+
+	pool.removeEntry(entry.tx.Hash())
+	_, ok = pool.conflict(tx)
+	assert.False(t, ok)
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"github.com/elastos/Elastos.ELA/core/contract"
 	"math"
-	"math/big"
+	"unicode/utf8"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
 
 	"github.com/elastos/Elastos.ELA.SideChain/blockchain"
 	"github.com/elastos/Elastos.ELA.SideChain/config"
@@ -18,16 +21,34 @@ import (
 const (
 	MinRegisterAssetTxFee = 1000000000
 	CheckRegisterAssetTx  = "checkregisterassettx"
+	CheckTokenDust        = "checktokendust"
+	CheckOutputMemo       = "checkoutputmemo"
 )
 
+// namedCheck is a check function registered into the validator.
+type namedCheck struct {
+	name     string
+	function func(*types.Transaction) error
+}
+
 type validator struct {
 	*mempool.Validator
 	chainParams *config.Params
 	spvService  *spv.Service
 	db          *blockchain.ChainStore
+	assetParams *params.AssetParams
+	policy      *Policy
+	orphans     *OrphanPool
+
+	sanityChecks  []namedCheck
+	contextChecks []namedCheck
 }
 
 func NewValidator(cfg *Config) *mempool.Validator {
+	return newValidator(cfg).Validator
+}
+
+func newValidator(cfg *Config) *validator {
 	var val validator
 	val.Validator = mempool.NewValidator(&mempool.Config{
 		ChainParams: cfg.ChainParams,
@@ -39,14 +60,31 @@ func NewValidator(cfg *Config) *mempool.Validator {
 	val.chainParams = cfg.ChainParams
 	val.spvService = cfg.SpvService
 	val.db = cfg.ChainStore
+	val.assetParams = cfg.AssetParams
+	val.policy = cfg.Policy
+	val.orphans = cfg.Orphans
+
+	val.registerSanityFunc(mempool.FuncNames.CheckTransactionOutput, val.checkTransactionOutputImpl)
+	val.registerSanityFunc(mempool.FuncNames.CheckAssetPrecision, val.checkAssetPrecisionImpl)
+	val.registerSanityFunc(mempool.FuncNames.CheckTransactionPayload, val.checkTransactionPayloadImpl)
+	val.registerContextFunc(mempool.FuncNames.CheckTransactionBalance, val.checkTransactionBalanceImpl)
+	val.registerContextFunc(mempool.FuncNames.CheckReferencedOutput, val.checkReferencedOutputImpl)
+	val.registerContextFunc(CheckRegisterAssetTx, val.CheckRegisterAssetTx)
+	val.registerContextFunc(CheckOutputMemo, val.checkOutputMemoImpl)
+	if val.policy != nil {
+		val.registerSanityFunc(CheckTokenDust, val.checkTokenDustImpl)
+	}
+	return &val
+}
 
-	val.RegisterSanityFunc(mempool.FuncNames.CheckTransactionOutput, val.checkTransactionOutputImpl)
-	val.RegisterSanityFunc(mempool.FuncNames.CheckAssetPrecision, val.checkAssetPrecisionImpl)
-	val.RegisterSanityFunc(mempool.FuncNames.CheckTransactionPayload, val.checkTransactionPayloadImpl)
-	val.RegisterContextFunc(mempool.FuncNames.CheckTransactionBalance, val.checkTransactionBalanceImpl)
-	val.RegisterContextFunc(mempool.FuncNames.CheckReferencedOutput, val.checkReferencedOutputImpl)
-	val.RegisterContextFunc(CheckRegisterAssetTx, val.CheckRegisterAssetTx)
-	return val.Validator
+func (v *validator) registerSanityFunc(name string, function func(*types.Transaction) error) {
+	v.RegisterSanityFunc(name, function)
+	v.sanityChecks = append(v.sanityChecks, namedCheck{name, function})
+}
+
+func (v *validator) registerContextFunc(name string, function func(*types.Transaction) error) {
+	v.RegisterContextFunc(name, function)
+	v.contextChecks = append(v.contextChecks, namedCheck{name, function})
 }
 
 func (v *validator) checkTransactionOutputImpl(txn *types.Transaction) error {
@@ -86,7 +124,7 @@ func (v *validator) checkTransactionOutputImpl(txn *types.Transaction) error {
 			if txn.IsRechargeToSideChainTx() || txn.IsTransferCrossChainAssetTx() {
 				return errors.New("cross chain asset tx asset id should only be ela asset id")
 			}
-			if output.TokenValue.Sign() < 0 || output.Value != 0 {
+			if err := core.NewTokenAmount(&output.TokenValue).Check(); err != nil || output.Value != 0 {
 				return errors.New("invalid transaction output with token asset id")
 			}
 		}
@@ -122,6 +160,7 @@ func (v *validator) checkReferencedOutputImpl(txn *types.Transaction) error {
 		referTxnOutIndex := input.Previous.Index
 		referTxn, _, err := v.db.GetTransaction(referHash)
 		if err != nil {
+			v.addOrphan(txn)
 			desc := "Referenced transaction can not be found" + common.BytesToHexString(referHash.Bytes())
 			return mempool.RuleError{ErrorCode: mempool.ErrUnknownReferedTx, Description: desc}
 		}
@@ -150,6 +189,44 @@ func (v *validator) checkReferencedOutputImpl(txn *types.Transaction) error {
 	return nil
 }
 
+// addOrphan keeps the transaction in the orphan pool if any of it's referenced
+// transactions can not be found, tagged by the peer which announced it.
+func (v *validator) addOrphan(txn *types.Transaction) {
+	if v.orphans == nil {
+		return
+	}
+
+	var missing []types.OutPoint
+	for _, input := range txn.Inputs {
+		if _, _, err := v.db.GetTransaction(input.Previous.TxID); err != nil {
+			missing = append(missing, input.Previous)
+		}
+	}
+	v.orphans.AddOrphan(txn, missing, v.orphans.Source(txn.Hash()))
+}
+
+// checkOutputMemoImpl rejects invalid output memo attributes and memos which
+// are not valid UTF-8 text.  Memo attributes are not interpreted as output
+// memos before they are activated, so existing transactions are still valid.
+func (v *validator) checkOutputMemoImpl(txn *types.Transaction) error {
+	height := v.db.GetHeight() + 1
+	if v.assetParams == nil || !v.assetParams.IsOutputMemoActive(height) {
+		return nil
+	}
+	memos, err := core.OutputMemos(txn)
+	if err != nil {
+		desc := fmt.Sprintf("[checkOutputMemo] %s", err)
+		return mempool.RuleError{ErrorCode: ErrOutputMemo, Description: desc}
+	}
+	for i, memo := range memos {
+		if !utf8.Valid(memo) {
+			desc := fmt.Sprintf("[checkOutputMemo] memo of output %d is not valid UTF-8", i)
+			return mempool.RuleError{ErrorCode: ErrOutputMemo, Description: desc}
+		}
+	}
+	return nil
+}
+
 func (v *validator) CheckRegisterAssetTx(txn *types.Transaction) error {
 	if txn.TxType == types.RegisterAsset {
 		if err := v.checkRegisterAssetTransaction(txn); err != nil {
@@ -181,30 +258,32 @@ func (v *validator) checkRegisterAssetTransaction(txn *types.Transaction) error
 		}
 	}
 
+	height := v.db.GetHeight() + 1
+	if v.assetParams != nil && v.assetParams.IsReserved(payload.Asset.Name, height) {
+		return fmt.Errorf("asset name %s is reserved", payload.Asset.Name)
+	}
+
 	for _, char := range payload.Asset.Description {
 		if 32 > char || char > 126 {
 			return fmt.Errorf("allow only ASCII characters in asset description")
 		}
 	}
 
-	for _, asset := range assets {
-		if asset.Name == payload.Asset.Name {
-			return fmt.Errorf("Asset name has been registed, " + asset.Name)
-		}
+	if err := checkAssetName(payload.Asset.Name, assets, v.assetParams, height); err != nil {
+		return err
 	}
 
 	//amount and program hash should be same in output and payload
-	totalToken := big.NewInt(0)
+	totalToken := new(core.TokenAmount)
 	for _, output := range txn.Outputs {
 		if output.AssetID.IsEqual(payload.Asset.Hash()) {
 			if !output.ProgramHash.IsEqual(payload.Controller) {
 				return fmt.Errorf("Register asset program hash not same as program hash in payload")
 			}
-			totalToken.Add(totalToken, &output.TokenValue)
+			totalToken.Add(core.NewTokenAmount(&output.TokenValue))
 		}
 	}
-	regAmount := big.NewInt(payload.Amount.IntValue())
-	regAmount.Mul(regAmount, getPrecisionBigInt())
+	regAmount := core.TokenAmountFromUnits(payload.Amount.IntValue())
 
 	if totalToken.Cmp(regAmount) != 0 {
 		return fmt.Errorf("Invalid register asset amount")
@@ -213,25 +292,25 @@ func (v *validator) checkRegisterAssetTransaction(txn *types.Transaction) error
 	return nil
 }
 
-func checkAmountPrecise(amount common.Fixed64, precision byte, assetPrecision byte) bool {
-	return amount.IntValue()%int64(math.Pow10(int(assetPrecision-precision))) == 0
-}
-
-func checkTokenAmountPrecise(amount big.Int, precision byte, assetPrecision byte) bool {
-	value := amount.String()
-	var decimal string
-	if len(value) <= 18 {
-		decimal = value
-	} else {
-		decimal = value[len(value)-18:]
-	}
-	decimalValue, ok := new(big.Int).SetString(decimal, 10)
-	if !ok {
-		fmt.Errorf("invalid token amount")
-		return false
+// checkAssetName checks the asset name does not conflict with the registered
+// assets, look-alike names conflict since the canonical names are activated.
+func checkAssetName(name string, assets map[common.Uint256]*types.Asset,
+	assetParams *params.AssetParams, height uint32) error {
+	for assetID, asset := range assets {
+		conflict := asset.Name == name
+		if assetParams != nil {
+			conflict = assetParams.IsNameConflict(name, asset.Name, height)
+		}
+		if conflict {
+			return fmt.Errorf("asset name %s conflicts with registered asset %s, id %s",
+				name, asset.Name, common.BytesToHexString(common.BytesReverse(assetID.Bytes())))
+		}
 	}
+	return nil
+}
 
-	return decimalValue.Int64()%int64(math.Pow10(int(assetPrecision-precision))) == 0
+func checkAmountPrecise(amount common.Fixed64, precision byte, assetPrecision byte) bool {
+	return amount.IntValue()%int64(math.Pow10(int(assetPrecision-precision))) == 0
 }
 
 func (v *validator) checkAssetPrecisionImpl(txn *types.Transaction) error {
@@ -261,7 +340,7 @@ func (v *validator) checkAssetPrecisionImpl(txn *types.Transaction) error {
 					return mempool.RuleError{ErrorCode: mempool.ErrAssetPrecision, Description: desc}
 				}
 			} else {
-				if !checkTokenAmountPrecise(output.TokenValue, precision, 18) {
+				if !core.NewTokenAmount(&output.TokenValue).IsPrecise(precision) {
 					desc := fmt.Sprint("[checkAssetPrecision] Invalide asset value,out of precise.")
 					return mempool.RuleError{ErrorCode: mempool.ErrAssetPrecision, Description: desc}
 				}
@@ -272,6 +351,45 @@ func (v *validator) checkAssetPrecisionImpl(txn *types.Transaction) error {
 	return nil
 }
 
+func (v *validator) checkTokenDustImpl(txn *types.Transaction) error {
+	if txn.IsCoinBaseTx() || txn.IsRegisterAssetTx() {
+		return nil
+	}
+
+	assetOutputs := make(map[common.Uint256][]*types.Output)
+	for _, output := range txn.Outputs {
+		if output.AssetID.IsEqual(v.chainParams.ElaAssetId) {
+			continue
+		}
+		assetOutputs[output.AssetID] = append(assetOutputs[output.AssetID], output)
+	}
+
+	for assetID, outputs := range assetOutputs {
+		if max := v.policy.MaxTokenOutputsPerAsset; max > 0 && len(outputs) > max {
+			desc := fmt.Sprintf("[checkTokenDust] %d outputs of asset %s exceeds the limit %d",
+				len(outputs), common.BytesToHexString(common.BytesReverse(assetID.Bytes())), max)
+			return mempool.RuleError{ErrorCode: ErrTooManyTokenOutputs, Description: desc}
+		}
+
+		// unknown asset will be reported by the asset precision check.
+		asset, err := v.db.GetAsset(assetID)
+		if err != nil {
+			continue
+		}
+		threshold := v.policy.DustThreshold(asset.Precision)
+		for _, output := range outputs {
+			value := core.NewTokenAmount(&output.TokenValue)
+			if value.Cmp(threshold) < 0 {
+				desc := fmt.Sprintf("[checkTokenDust] output value %s of asset %s is less than the minimum %s",
+					value.Format(asset.Precision), asset.Name, threshold.Format(asset.Precision))
+				return mempool.RuleError{ErrorCode: ErrTokenDust, Description: desc}
+			}
+		}
+	}
+
+	return nil
+}
+
 func (v *validator) checkTransactionPayloadImpl(txn *types.Transaction) error {
 	switch pld := txn.Payload.(type) {
 	case *types.PayloadRegisterAsset:
@@ -298,12 +416,13 @@ func (v *validator) checkTransactionPayloadImpl(txn *types.Transaction) error {
 
 func (v *validator) checkTransactionBalanceImpl(txn *types.Transaction) error {
 	var elaInputAmount = common.Fixed64(0)
-	var tokenInputAmount = new(big.Int).SetInt64(0)
+	var tokenInputAmount = new(core.TokenAmount)
 	var elaOutputAmount = common.Fixed64(0)
-	var tokenOutputAmount = new(big.Int).SetInt64(0)
+	var tokenOutputAmount = new(core.TokenAmount)
 
 	references, err := v.db.GetTxReference(txn)
 	if err != nil {
+		v.addOrphan(txn)
 		return err
 	}
 
@@ -311,14 +430,14 @@ func (v *validator) checkTransactionBalanceImpl(txn *types.Transaction) error {
 		if output.AssetID.IsEqual(v.chainParams.ElaAssetId) {
 			elaInputAmount += output.Value
 		} else {
-			tokenInputAmount.Add(tokenInputAmount, &(output.TokenValue))
+			tokenInputAmount.Add(core.NewTokenAmount(&output.TokenValue))
 		}
 	}
 	for _, output := range txn.Outputs {
 		if output.AssetID.IsEqual(v.chainParams.ElaAssetId) {
 			elaOutputAmount += output.Value
 		} else {
-			tokenOutputAmount.Add(tokenOutputAmount, &(output.TokenValue))
+			tokenOutputAmount.Add(core.NewTokenAmount(&output.TokenValue))
 		}
 	}
 
@@ -328,7 +447,7 @@ func (v *validator) checkTransactionBalanceImpl(txn *types.Transaction) error {
 			return errors.New("crosschain transaction fee is not enough")
 		}
 	} else if txn.IsRegisterAssetTx() {
-		if int(elaBalance) < MinRegisterAssetTxFee {
+		if elaBalance < v.registerAssetFee(txn) {
 			return errors.New("register asset transaction fee is not enough")
 		}
 	} else {
@@ -337,15 +456,20 @@ func (v *validator) checkTransactionBalanceImpl(txn *types.Transaction) error {
 		}
 	}
 
-	tokenBalance := tokenInputAmount.Sub(tokenInputAmount, tokenOutputAmount)
+	tokenBalance := tokenInputAmount.Sub(tokenOutputAmount)
 	if txn.TxType != types.RegisterAsset && tokenBalance.Sign() != 0 {
 		return errors.New("token amount is not balanced")
 	}
 	return nil
 }
 
-func getPrecisionBigInt() *big.Int {
-	value := big.Int{}
-	value.SetString("1000000000000000000", 10)
-	return &value
+// registerAssetFee returns the minimum fee of the register asset transaction,
+// which depends on the length of the asset name since the premium fees are
+// activated.
+func (v *validator) registerAssetFee(txn *types.Transaction) common.Fixed64 {
+	payload, ok := txn.Payload.(*types.PayloadRegisterAsset)
+	if !ok || v.assetParams == nil {
+		return MinRegisterAssetTxFee
+	}
+	return v.assetParams.RegisterFee(payload.Asset.Name, v.db.GetHeight()+1)
 }
@@ -0,0 +1,34 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckAssetName(t *testing.T) {
+	assetParams := params.MainNetAssetParams
+	assetParams.CanonicalNameHeight = 100
+
+	// A look-alike pair registered before the activation is still accepted
+	// when the chain is resynced.
+	assets := map[common.Uint256]*types.Asset{
+		{0x01}: {Name: "Modem"},
+	}
+	assert.NoError(t, checkAssetName("Modern", assets, &assetParams, 99))
+	assets[common.Uint256{0x02}] = &types.Asset{Name: "Modern"}
+	assert.NoError(t, checkAssetName("G0LD", assets, &assetParams, 99))
+
+	assert.Error(t, checkAssetName("Modem", assets, &assetParams, 99))
+	assert.Error(t, checkAssetName("rnodern", assets, &assetParams, 100))
+	assert.NoError(t, checkAssetName("rnodern", assets, &assetParams, 99))
+	assert.NoError(t, checkAssetName("GOLD", assets, &assetParams, 100))
+
+	// Only the same names conflict without the asset params.
+	assert.Error(t, checkAssetName("Modem", assets, nil, 100))
+	assert.NoError(t, checkAssetName("rnodern", assets, nil, 100))
+}
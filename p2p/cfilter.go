@@ -0,0 +1,154 @@
+package p2p
+
+import (
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/p2p/msg"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/p2p"
+)
+
+// CFilterService serves the block filters and filter headers to peers.
+type CFilterService struct {
+	store *blockchain.TokenChainStore
+}
+
+// BlockRange returns the hashes of blocks from the start height to the stop
+// hash, the stop block must be in the main chain and the range can not exceed
+// maxBlocks.
+func (s *CFilterService) BlockRange(startHeight uint32, stopHash common.Uint256,
+	maxBlocks uint32) ([]common.Uint256, error) {
+	stopHeader, err := s.store.GetHeader(stopHash)
+	if err != nil {
+		return nil, fmt.Errorf("unknown stop block %s", stopHash)
+	}
+	stopHeight := stopHeader.Height
+	if hash, err := s.store.GetBlockHash(stopHeight); err != nil || !hash.IsEqual(stopHash) {
+		return nil, fmt.Errorf("stop block %s not in main chain", stopHash)
+	}
+	if startHeight > stopHeight {
+		return nil, fmt.Errorf("start height %d is higher than stop height %d",
+			startHeight, stopHeight)
+	}
+	if stopHeight-startHeight >= maxBlocks {
+		return nil, fmt.Errorf("requested %d blocks exceeds limit %d",
+			stopHeight-startHeight+1, maxBlocks)
+	}
+
+	hashes := make([]common.Uint256, 0, stopHeight-startHeight+1)
+	for height := startHeight; height <= stopHeight; height++ {
+		hash, err := s.store.GetBlockHash(height)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// HandleGetCFilters returns the block filters requested by the message.
+func (s *CFilterService) HandleGetCFilters(m *msg.GetCFilters) ([]*msg.CFilter, error) {
+	if m.FilterType != blockchain.BlockFilterType {
+		return nil, fmt.Errorf("unknown filter type %d", m.FilterType)
+	}
+	hashes, err := s.BlockRange(m.StartHeight, m.StopHash, msg.MaxGetCFiltersReqRange)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := make([]*msg.CFilter, 0, len(hashes))
+	for _, hash := range hashes {
+		filter, err := s.store.GetBlockFilter(hash)
+		if err != nil {
+			return nil, fmt.Errorf("block filter of %s not found", hash)
+		}
+		filters = append(filters, &msg.CFilter{
+			FilterType: m.FilterType,
+			BlockHash:  hash,
+			Filter:     filter,
+		})
+	}
+	return filters, nil
+}
+
+// HandleGetCFHeaders returns the filter headers requested by the message.
+func (s *CFilterService) HandleGetCFHeaders(m *msg.GetCFHeaders) (*msg.CFHeaders, error) {
+	if m.FilterType != blockchain.BlockFilterType {
+		return nil, fmt.Errorf("unknown filter type %d", m.FilterType)
+	}
+	hashes, err := s.BlockRange(m.StartHeight, m.StopHash, msg.MaxCFHeadersPerMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := &msg.CFHeaders{
+		FilterType:   m.FilterType,
+		StopHash:     m.StopHash,
+		FilterHashes: make([]common.Uint256, 0, len(hashes)),
+	}
+	if m.StartHeight > 0 {
+		prevHash, err := s.store.GetBlockHash(m.StartHeight - 1)
+		if err != nil {
+			return nil, err
+		}
+		headers.PrevFilterHeader, err = s.store.GetFilterHeader(prevHash)
+		if err != nil {
+			return nil, fmt.Errorf("filter header of %s not found", prevHash)
+		}
+	}
+	for _, hash := range hashes {
+		filter, err := s.store.GetBlockFilter(hash)
+		if err != nil {
+			return nil, fmt.Errorf("block filter of %s not found", hash)
+		}
+		headers.FilterHashes = append(headers.FilterHashes, blockchain.FilterHash(filter))
+	}
+	return headers, nil
+}
+
+// HandleMessage handles the block filter requests, and returns the messages
+// should be sent back to the peer.  Other messages are ignored.
+func (s *CFilterService) HandleMessage(m p2p.Message) ([]p2p.Message, error) {
+	switch m := m.(type) {
+	case *msg.GetCFilters:
+		filters, err := s.HandleGetCFilters(m)
+		if err != nil {
+			return nil, err
+		}
+		replies := make([]p2p.Message, 0, len(filters))
+		for _, filter := range filters {
+			replies = append(replies, filter)
+		}
+		return replies, nil
+
+	case *msg.GetCFHeaders:
+		headers, err := s.HandleGetCFHeaders(m)
+		if err != nil {
+			return nil, err
+		}
+		return []p2p.Message{headers}, nil
+	}
+	return nil, nil
+}
+
+// MakeEmptyMessage returns an empty block filter message of the command, it
+// returns nil if the command is not a block filter message.
+func MakeEmptyMessage(cmd string) p2p.Message {
+	switch cmd {
+	case msg.CmdGetCFilters:
+		return &msg.GetCFilters{}
+	case msg.CmdCFilter:
+		return &msg.CFilter{}
+	case msg.CmdGetCFHeaders:
+		return &msg.GetCFHeaders{}
+	case msg.CmdCFHeaders:
+		return &msg.CFHeaders{}
+	}
+	return nil
+}
+
+func NewCFilterService(store *blockchain.TokenChainStore) *CFilterService {
+	return &CFilterService{store: store}
+}
@@ -0,0 +1,82 @@
+package p2p
+
+import (
+	"sync"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/p2p/msg"
+
+	"github.com/elastos/Elastos.ELA.SideChain/peer"
+	"github.com/elastos/Elastos.ELA/p2p"
+	elamsg "github.com/elastos/Elastos.ELA/p2p/msg"
+)
+
+// MessageHandler handles the peer messages of the token node features, it is
+// invoked by the P2P server before the side chain handles the message.
+type MessageHandler struct {
+	orphans  *mempool.OrphanPool
+	cfilters *CFilterService
+
+	mtx   sync.Mutex
+	peers map[uint64]struct{}
+}
+
+// HandleMessage serves the block filter requests of peers, and records the
+// peers which announce transactions, so the orphan transactions are tagged by
+// the peer ID and removed when the peer is disconnected.
+func (h *MessageHandler) HandleMessage(p *peer.Peer, m p2p.Message) {
+	switch m := m.(type) {
+	case *msg.GetCFilters, *msg.GetCFHeaders:
+		if h.cfilters == nil {
+			return
+		}
+		replies, err := h.cfilters.HandleMessage(m)
+		if err != nil {
+			// Peers requesting unknown or too many blocks are misbehaving.
+			p.Disconnect()
+			return
+		}
+		for _, reply := range replies {
+			p.QueueMessage(reply, nil)
+		}
+
+	case *elamsg.Inv:
+		if h.orphans == nil {
+			return
+		}
+		h.trackPeer(p)
+		tag := mempool.Tag(p.ID())
+		for _, iv := range m.InvList {
+			if iv.Type == elamsg.InvTypeTx {
+				h.orphans.SetSource(iv.Hash, tag)
+			}
+		}
+	}
+}
+
+// trackPeer removes the orphan transactions of the peer once it disconnects.
+func (h *MessageHandler) trackPeer(p *peer.Peer) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	id := p.ID()
+	if _, ok := h.peers[id]; ok {
+		return
+	}
+	h.peers[id] = struct{}{}
+	go func() {
+		p.WaitForDisconnect()
+		h.orphans.RemoveOrphansByTag(mempool.Tag(id))
+		h.mtx.Lock()
+		delete(h.peers, id)
+		h.mtx.Unlock()
+	}()
+}
+
+func NewMessageHandler(orphans *mempool.OrphanPool, cfilters *CFilterService) *MessageHandler {
+	return &MessageHandler{
+		orphans:  orphans,
+		cfilters: cfilters,
+		peers:    make(map[uint64]struct{}),
+	}
+}
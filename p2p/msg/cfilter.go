@@ -0,0 +1,205 @@
+package msg
+
+import (
+	"io"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/p2p"
+)
+
+const (
+	CmdGetCFilters  = "getcfilters"
+	CmdCFilter      = "cfilter"
+	CmdGetCFHeaders = "getcfheaders"
+	CmdCFHeaders    = "cfheaders"
+
+	// MaxGetCFiltersReqRange is the maximum number of filters can be
+	// requested by a getcfilters message.
+	MaxGetCFiltersReqRange = 1000
+
+	// MaxCFHeadersPerMsg is the maximum number of filter hashes in a
+	// cfheaders message.
+	MaxCFHeadersPerMsg = 2000
+
+	// MaxCFilterDataSize is the maximum size of a block filter.
+	MaxCFilterDataSize = 256 * 1024
+)
+
+// Ensure the messages implement the p2p.Message interface.
+var (
+	_ p2p.Message = (*GetCFilters)(nil)
+	_ p2p.Message = (*CFilter)(nil)
+	_ p2p.Message = (*GetCFHeaders)(nil)
+	_ p2p.Message = (*CFHeaders)(nil)
+)
+
+// GetCFilters requests the block filters of blocks from StartHeight to the
+// block StopHash.
+type GetCFilters struct {
+	FilterType  uint8
+	StartHeight uint32
+	StopHash    common.Uint256
+}
+
+func (msg *GetCFilters) CMD() string {
+	return CmdGetCFilters
+}
+
+func (msg *GetCFilters) MaxLength() uint32 {
+	return 1 + 4 + 32
+}
+
+func (msg *GetCFilters) Serialize(w io.Writer) error {
+	return serializeRequest(w, msg.FilterType, msg.StartHeight, &msg.StopHash)
+}
+
+func (msg *GetCFilters) Deserialize(r io.Reader) error {
+	return deserializeRequest(r, &msg.FilterType, &msg.StartHeight, &msg.StopHash)
+}
+
+// CFilter is the block filter of a block, sent in response of getcfilters.
+type CFilter struct {
+	FilterType uint8
+	BlockHash  common.Uint256
+	Filter     []byte
+}
+
+func (msg *CFilter) CMD() string {
+	return CmdCFilter
+}
+
+func (msg *CFilter) MaxLength() uint32 {
+	return 1 + 32 + 5 + MaxCFilterDataSize
+}
+
+func (msg *CFilter) Serialize(w io.Writer) error {
+	if err := common.WriteUint8(w, msg.FilterType); err != nil {
+		return err
+	}
+	if err := msg.BlockHash.Serialize(w); err != nil {
+		return err
+	}
+	return common.WriteVarBytes(w, msg.Filter)
+}
+
+func (msg *CFilter) Deserialize(r io.Reader) error {
+	var err error
+	if msg.FilterType, err = common.ReadUint8(r); err != nil {
+		return err
+	}
+	if err = msg.BlockHash.Deserialize(r); err != nil {
+		return err
+	}
+	msg.Filter, err = common.ReadVarBytes(r, MaxCFilterDataSize, "filter")
+	return err
+}
+
+// GetCFHeaders requests the filter headers of blocks from StartHeight to the
+// block StopHash.
+type GetCFHeaders struct {
+	FilterType  uint8
+	StartHeight uint32
+	StopHash    common.Uint256
+}
+
+func (msg *GetCFHeaders) CMD() string {
+	return CmdGetCFHeaders
+}
+
+func (msg *GetCFHeaders) MaxLength() uint32 {
+	return 1 + 4 + 32
+}
+
+func (msg *GetCFHeaders) Serialize(w io.Writer) error {
+	return serializeRequest(w, msg.FilterType, msg.StartHeight, &msg.StopHash)
+}
+
+func (msg *GetCFHeaders) Deserialize(r io.Reader) error {
+	return deserializeRequest(r, &msg.FilterType, &msg.StartHeight, &msg.StopHash)
+}
+
+// CFHeaders is the filter hashes of blocks to the block StopHash, with the
+// filter header of the block before the first one, so the filter headers can
+// be connected and verified.
+type CFHeaders struct {
+	FilterType       uint8
+	StopHash         common.Uint256
+	PrevFilterHeader common.Uint256
+	FilterHashes     []common.Uint256
+}
+
+func (msg *CFHeaders) CMD() string {
+	return CmdCFHeaders
+}
+
+func (msg *CFHeaders) MaxLength() uint32 {
+	return 1 + 32 + 32 + 5 + 32*MaxCFHeadersPerMsg
+}
+
+func (msg *CFHeaders) Serialize(w io.Writer) error {
+	if err := common.WriteUint8(w, msg.FilterType); err != nil {
+		return err
+	}
+	if err := msg.StopHash.Serialize(w); err != nil {
+		return err
+	}
+	if err := msg.PrevFilterHeader.Serialize(w); err != nil {
+		return err
+	}
+	if err := common.WriteVarUint(w, uint64(len(msg.FilterHashes))); err != nil {
+		return err
+	}
+	for _, hash := range msg.FilterHashes {
+		if err := hash.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (msg *CFHeaders) Deserialize(r io.Reader) error {
+	var err error
+	if msg.FilterType, err = common.ReadUint8(r); err != nil {
+		return err
+	}
+	if err = msg.StopHash.Deserialize(r); err != nil {
+		return err
+	}
+	if err = msg.PrevFilterHeader.Deserialize(r); err != nil {
+		return err
+	}
+	count, err := common.ReadVarUint(r, MaxCFHeadersPerMsg)
+	if err != nil {
+		return err
+	}
+	msg.FilterHashes = make([]common.Uint256, count)
+	for i := range msg.FilterHashes {
+		if err := msg.FilterHashes[i].Deserialize(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func serializeRequest(w io.Writer, filterType uint8, startHeight uint32,
+	stopHash *common.Uint256) error {
+	if err := common.WriteUint8(w, filterType); err != nil {
+		return err
+	}
+	if err := common.WriteUint32(w, startHeight); err != nil {
+		return err
+	}
+	return stopHash.Serialize(w)
+}
+
+func deserializeRequest(r io.Reader, filterType *uint8, startHeight *uint32,
+	stopHash *common.Uint256) error {
+	var err error
+	if *filterType, err = common.ReadUint8(r); err != nil {
+		return err
+	}
+	if *startHeight, err = common.ReadUint32(r); err != nil {
+		return err
+	}
+	return stopHash.Deserialize(r)
+}
@@ -0,0 +1,49 @@
+package msg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/p2p"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCFilterMessagesRoundTrip(t *testing.T) {
+	messages := []struct {
+		msg   p2p.Message
+		empty p2p.Message
+	}{
+		{&GetCFilters{StartHeight: 10, StopHash: common.Uint256{0x01}}, &GetCFilters{}},
+		{&CFilter{BlockHash: common.Uint256{0x02}, Filter: []byte{0x03, 0x04}}, &CFilter{}},
+		{&CFilter{BlockHash: common.Uint256{0x02}, Filter: []byte{}}, &CFilter{}},
+		{&GetCFHeaders{StartHeight: 1, StopHash: common.Uint256{0x05}}, &GetCFHeaders{}},
+		{&CFHeaders{
+			StopHash:         common.Uint256{0x06},
+			PrevFilterHeader: common.Uint256{0x07},
+			FilterHashes:     []common.Uint256{{0x08}, {0x09}},
+		}, &CFHeaders{}},
+		{&CFHeaders{StopHash: common.Uint256{0x06}, FilterHashes: []common.Uint256{}}, &CFHeaders{}},
+	}
+
+	for _, m := range messages {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, m.msg.Serialize(buf))
+		data := buf.Bytes()
+		assert.True(t, uint32(len(data)) <= m.msg.MaxLength())
+
+		assert.NoError(t, m.empty.Deserialize(bytes.NewReader(data)))
+		assert.Equal(t, m.msg, m.empty)
+		assert.Equal(t, m.msg.CMD(), m.empty.CMD())
+
+		for i := 0; i < len(data); i++ {
+			assert.Error(t, m.empty.Deserialize(bytes.NewReader(data[:i])))
+		}
+	}
+
+	// The number of filter hashes exceeds MaxCFHeadersPerMsg.
+	buf := new(bytes.Buffer)
+	headers := CFHeaders{FilterHashes: make([]common.Uint256, MaxCFHeadersPerMsg+1)}
+	assert.NoError(t, headers.Serialize(buf))
+	assert.Error(t, new(CFHeaders).Deserialize(buf))
+}
@@ -0,0 +1,121 @@
+package params
+
+import (
+	"math"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// AssetFeeTier defines the register fee of asset names which length is not
+// longer than MaxNameLength.
+type AssetFeeTier struct {
+	MaxNameLength int
+	Fee           common.Fixed64
+}
+
+// AssetParams defines the asset registration rules of a network.
+type AssetParams struct {
+	// MinRegisterFee is the register fee of asset names that longer than
+	// all fee tiers.
+	MinRegisterFee common.Fixed64
+
+	// FeeTiers defines the premium register fees of short asset names, it
+	// must be sorted by MaxNameLength in ascending order.
+	FeeTiers []AssetFeeTier
+
+	// ReservedNames is the asset names that can not be registered by anyone.
+	ReservedNames []string
+
+	// PremiumFeeHeight is the height from which the fees of FeeTiers and the
+	// ReservedNames are applied, assets registered below it only pay the
+	// MinRegisterFee.
+	PremiumFeeHeight uint32
+
+	// CanonicalNameHeight is the height from which asset names must differ
+	// from registered names in their canonical forms, below it only the same
+	// names conflict.
+	CanonicalNameHeight uint32
+
+	// OutputMemoHeight is the height from which token outputs can carry a
+	// memo.
+	OutputMemoHeight uint32
+}
+
+// IsOutputMemoActive returns if token outputs can carry a memo at the given
+// height.
+func (p *AssetParams) IsOutputMemoActive(height uint32) bool {
+	return height >= p.OutputMemoHeight
+}
+
+// RegisterFee returns the minimum fee to register an asset with the given name
+// at the given height.
+func (p *AssetParams) RegisterFee(name string, height uint32) common.Fixed64 {
+	if height < p.PremiumFeeHeight {
+		return p.MinRegisterFee
+	}
+	for _, tier := range p.FeeTiers {
+		if len(name) <= tier.MaxNameLength {
+			return tier.Fee
+		}
+	}
+	return p.MinRegisterFee
+}
+
+// IsReserved returns if the given asset name or a name looks alike it is
+// reserved by the network at the given height.
+func (p *AssetParams) IsReserved(name string, height uint32) bool {
+	if height < p.PremiumFeeHeight {
+		return false
+	}
+	canonical := core.CanonicalAssetName(name)
+	for _, reserved := range p.ReservedNames {
+		if core.CanonicalAssetName(reserved) == canonical {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNameConflict returns if the asset name can not be registered at the given
+// height because of the registered name.
+func (p *AssetParams) IsNameConflict(name, registered string, height uint32) bool {
+	if height < p.CanonicalNameHeight {
+		return name == registered
+	}
+	return core.CanonicalAssetName(name) == core.CanonicalAssetName(registered)
+}
+
+// MainNetAssetParams defines the asset registration rules for the main network.
+var MainNetAssetParams = AssetParams{
+	MinRegisterFee: 1000000000, // 10 ELA
+	FeeTiers: []AssetFeeTier{
+		{MaxNameLength: 1, Fee: 1000000000000}, // 10000 ELA
+		{MaxNameLength: 2, Fee: 100000000000},  // 1000 ELA
+		{MaxNameLength: 3, Fee: 10000000000},   // 100 ELA
+		{MaxNameLength: 4, Fee: 5000000000},    // 50 ELA
+	},
+	ReservedNames: []string{
+		"ELA", "BTC", "ETH", "USDT", "USDC", "BNB",
+		"EOS", "XRP", "LTC", "BCH", "TRX", "DAI",
+	},
+	PremiumFeeHeight:    math.MaxUint32, // not activated yet
+	CanonicalNameHeight: math.MaxUint32, // not activated yet
+	OutputMemoHeight:    math.MaxUint32, // not activated yet
+}
+
+// TestNetAssetParams defines the asset registration rules for the test network.
+var TestNetAssetParams = MainNetAssetParams
+
+// RegNetAssetParams defines the asset registration rules for the regression
+// network.
+var RegNetAssetParams = regNetAssetParams()
+
+func regNetAssetParams() AssetParams {
+	params := MainNetAssetParams
+	params.PremiumFeeHeight = 0
+	params.CanonicalNameHeight = 0
+	params.OutputMemoHeight = 0
+	return params
+}
@@ -0,0 +1,33 @@
+package params
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterFee(t *testing.T) {
+	p := MainNetAssetParams
+	p.PremiumFeeHeight = 100
+
+	// Assets registered before the activation only pay the minimum fee.
+	assert.Equal(t, p.MinRegisterFee, p.RegisterFee("A", 99))
+	assert.False(t, p.IsReserved("USDT", 99))
+
+	assert.Equal(t, p.FeeTiers[0].Fee, p.RegisterFee("A", 100))
+	assert.Equal(t, p.FeeTiers[3].Fee, p.RegisterFee("GOLD", 100))
+	assert.Equal(t, p.MinRegisterFee, p.RegisterFee("TOKENS", 100))
+	assert.True(t, p.IsReserved("USDT", 100))
+	assert.True(t, p.IsReserved("U5DT", 100))
+	assert.False(t, p.IsReserved("GOLD", 100))
+}
+
+func TestIsNameConflict(t *testing.T) {
+	p := MainNetAssetParams
+	p.CanonicalNameHeight = 100
+
+	assert.True(t, p.IsNameConflict("GOLD", "GOLD", 99))
+	assert.False(t, p.IsNameConflict("G0LD", "GOLD", 99))
+	assert.True(t, p.IsNameConflict("G0LD", "GOLD", 100))
+	assert.False(t, p.IsNameConflict("SILVER", "GOLD", 100))
+}
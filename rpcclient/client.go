@@ -0,0 +1,379 @@
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultTimeout is the timeout of a request if not configured.
+	defaultTimeout = 30 * time.Second
+
+	// dialTimeout is the timeout of connecting to the node.
+	dialTimeout = 10 * time.Second
+)
+
+// Config is the configuration of the JSON-RPC client.
+type Config struct {
+	// Address is the address of the node JSON-RPC server, in host:port such
+	// as "127.0.0.1:20616" or a http URL.
+	Address string
+
+	// User and Pass are the RPCUser and RPCPass of the node, the requests are
+	// not authorized if both are empty.
+	User string
+	Pass string
+
+	// LocalAddr is the local IP address the client connects from, it must
+	// be in the RPCWhiteList of the node if the host has several addresses.
+	LocalAddr string
+
+	// Timeout is the timeout of a request including the batch ones, default
+	// is 30 seconds.
+	Timeout time.Duration
+
+	// HTTPClient replaces the client created from LocalAddr and Timeout if
+	// it is not nil.
+	HTTPClient *http.Client
+}
+
+// Client is a JSON-RPC client of the token node.
+type Client struct {
+	url  string
+	user string
+	pass string
+	http *http.Client
+
+	id uint64
+
+	// noBatch is set once the node is found not supporting batch requests,
+	// the batches are sent one by one after then.
+	noBatch int32
+}
+
+// New creates a client of the node JSON-RPC server.
+func New(cfg *Config) (*Client, error) {
+	if len(cfg.Address) == 0 {
+		return nil, errors.New("rpcclient: address not configured")
+	}
+	url := cfg.Address
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		transport, err := newTransport(cfg.LocalAddr)
+		if err != nil {
+			return nil, err
+		}
+		timeout := cfg.Timeout
+		if timeout == 0 {
+			timeout = defaultTimeout
+		}
+		client = &http.Client{Transport: transport, Timeout: timeout}
+	}
+
+	return &Client{
+		url:  url,
+		user: cfg.User,
+		pass: cfg.Pass,
+		http: client,
+	}, nil
+}
+
+// newTransport returns the transport connecting to the node directly from the
+// local address.  The node accepts requests by the remote IP in its white
+// list, so the requests are never sent through proxies of the environment,
+// and IPv4 is preferred as the white list is usually "127.0.0.1" while
+// "localhost" may resolve to "::1" first.
+func newTransport(localAddr string) (*http.Transport, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}
+	preferIPv4 := true
+	if len(localAddr) > 0 {
+		ip := net.ParseIP(localAddr)
+		if ip == nil {
+			return nil, fmt.Errorf("rpcclient: invalid local address %s", localAddr)
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+		preferIPv4 = ip.To4() != nil
+	}
+
+	return &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if preferIPv4 && network == "tcp" {
+				if conn, err := dialer.DialContext(ctx, "tcp4", addr); err == nil {
+					return conn, nil
+				}
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}, nil
+}
+
+// Standard error codes of JSON-RPC 2.0.
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+)
+
+// Error is the error returned by the node, the message is the error of the
+// service like "wallet is not enabled".
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// decodeError decodes the error of a response, which is an object of code and
+// message, or a string by some versions of the node.
+func decodeError(data json.RawMessage) error {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	var e Error
+	if err := json.Unmarshal(data, &e); err == nil {
+		return &e
+	}
+	var message string
+	if err := json.Unmarshal(data, &message); err == nil {
+		return &Error{Code: InternalError, Message: message}
+	}
+	return &Error{Code: InternalError, Message: string(data)}
+}
+
+type request struct {
+	Version string      `json:"jsonrpc"`
+	ID      uint64      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// decode decodes the result of the response into result, result is ignored if
+// it is nil.
+func (r *response) decode(result interface{}) error {
+	if err := decodeError(r.Error); err != nil {
+		return err
+	}
+	if result == nil || len(r.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Result, result); err != nil {
+		return fmt.Errorf("rpcclient: invalid result, %s", err)
+	}
+	return nil
+}
+
+func (c *Client) newRequest(method string, params interface{}) *request {
+	if params == nil {
+		params = struct{}{}
+	}
+	return &request{
+		Version: "2.0",
+		ID:      atomic.AddUint64(&c.id, 1),
+		Method:  method,
+		Params:  params,
+	}
+}
+
+// statusError is returned if the node rejects the request by HTTP status.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return "rpcclient: " + e.status
+}
+
+// post posts the body to the node and returns the response body.
+func (c *Client) post(body interface{}) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(c.user) > 0 || len(c.pass) > 0 {
+		req.SetBasicAuth(c.user, c.pass)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// The node responses errors like unknown methods in JSON-RPC with
+		// non-OK statuses, only auth and white list failures are returned
+		// as status errors.
+		var r response
+		if json.Unmarshal(data, &r) == nil && len(r.Error) > 0 &&
+			resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+			return data, nil
+		}
+		return nil, &statusError{code: resp.StatusCode, status: resp.Status}
+	}
+	return data, nil
+}
+
+// Call calls the method with the params and decodes the result into result.
+// The params are sent by name, which is a struct or map of the parameters or
+// nil if the method has none.  The error is an *Error if the node returns an
+// error.
+func (c *Client) Call(method string, params interface{}, result interface{}) error {
+	r, err := c.do(method, params)
+	if err != nil {
+		return err
+	}
+	return r.decode(result)
+}
+
+// do sends a single request and returns the response.
+func (c *Client) do(method string, params interface{}) (*response, error) {
+	data, err := c.post(c.newRequest(method, params))
+	if err != nil {
+		return nil, err
+	}
+	var r response
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("rpcclient: invalid response, %s", err)
+	}
+	return &r, nil
+}
+
+// BatchCall is a call queued in a batch, Err is set after the batch is sent.
+type BatchCall struct {
+	Method string
+	Params interface{}
+	Result interface{}
+	Err    error
+}
+
+// Batch is a batch of calls sent in one request.
+type Batch struct {
+	client *Client
+	calls  []*BatchCall
+}
+
+// NewBatch returns an empty batch of the client.
+func (c *Client) NewBatch() *Batch {
+	return &Batch{client: c}
+}
+
+// Queue adds the call to the batch, the result is decoded into result after
+// the batch is sent.
+func (b *Batch) Queue(method string, params interface{}, result interface{}) *BatchCall {
+	call := &BatchCall{Method: method, Params: params, Result: result}
+	b.calls = append(b.calls, call)
+	return call
+}
+
+// Len returns the number of calls in the batch.
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Send sends the calls in one JSON-RPC batch request, the results and errors
+// of each call are set to the calls.  The calls are sent one by one if the
+// node does not support batch requests.  The error is returned only if the
+// request fails, such as the node is not reachable or not authorized.
+func (b *Batch) Send() error {
+	if len(b.calls) == 0 {
+		return nil
+	}
+	c := b.client
+	if atomic.LoadInt32(&c.noBatch) == 1 {
+		return b.sendOneByOne()
+	}
+
+	requests := make([]*request, 0, len(b.calls))
+	index := make(map[uint64]*BatchCall, len(b.calls))
+	for _, call := range b.calls {
+		req := c.newRequest(call.Method, call.Params)
+		requests = append(requests, req)
+		index[req.ID] = call
+	}
+
+	// Nodes not supporting batch requests reject them with 400 or 404, or
+	// respond a single error rather than an array, other failures are
+	// returned without sending the calls one by one.
+	data, err := c.post(requests)
+	if err != nil {
+		e, ok := err.(*statusError)
+		if !ok || (e.code != http.StatusBadRequest && e.code != http.StatusNotFound) {
+			return err
+		}
+		atomic.StoreInt32(&c.noBatch, 1)
+		return b.sendOneByOne()
+	}
+	var responses []response
+	if err := json.Unmarshal(data, &responses); err != nil {
+		var single response
+		if json.Unmarshal(data, &single) != nil {
+			return fmt.Errorf("rpcclient: invalid response, %s", err)
+		}
+		atomic.StoreInt32(&c.noBatch, 1)
+		return b.sendOneByOne()
+	}
+
+	for _, r := range responses {
+		var id uint64
+		if err := json.Unmarshal(r.ID, &id); err != nil {
+			continue
+		}
+		call, ok := index[id]
+		if !ok {
+			continue
+		}
+		call.Err = r.decode(call.Result)
+		delete(index, id)
+	}
+	for _, call := range index {
+		call.Err = errors.New("rpcclient: no response of the call")
+	}
+	return nil
+}
+
+func (b *Batch) sendOneByOne() error {
+	for _, call := range b.calls {
+		r, err := b.client.do(call.Method, call.Params)
+		if err != nil {
+			return err
+		}
+		call.Err = r.decode(call.Result)
+	}
+	return nil
+}
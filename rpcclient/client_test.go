@@ -0,0 +1,175 @@
+package rpcclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sv "github.com/elastos/Elastos.ELA.SideChain.Token/service"
+
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/utils/http/jsonrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestServer starts a JSON-RPC server of the actions that do not need the
+// blockchain.
+func newTestServer() *httptest.Server {
+	cfg := &sv.Config{}
+	cfg.GetPayloadInfo = sv.GetPayloadInfo
+	cfg.GetTransactionInfo = sv.GetTransactionInfo
+	httpService := sv.NewHttpService(cfg)
+
+	s := jsonrpc.NewServer(&jsonrpc.Config{
+		User:      "user",
+		Pass:      "pass",
+		WhiteList: []string{"127.0.0.1"},
+	})
+	s.RegisterAction("decoderawtransaction", httpService.DecodeRawTransaction, "data", "rawunits")
+	s.RegisterAction("createrawtransaction", httpService.CreateRawTransaction, "inputs", "outputs", "locktime",
+		"fromaddresses", "changeaddress", "fee", "strategy")
+	s.RegisterAction("getnewaddress", httpService.GetNewAddress, "password")
+	return httptest.NewServer(s)
+}
+
+func newTestClient(t *testing.T, server *httptest.Server, pass string) *Client {
+	client, err := New(&Config{Address: server.URL, User: "user", Pass: pass})
+	assert.NoError(t, err)
+	return client
+}
+
+func testRequest() *sv.CreateRawTransactionRequest {
+	address, _ := common.Uint168{0x21, 0x01}.ToAddress()
+	return &sv.CreateRawTransactionRequest{
+		Inputs: []sv.RawTxInput{{TxID: service.ToReversedString(common.Uint256{0x01}), VOut: 1}},
+		Outputs: []sv.RawTxOutput{{
+			Address: address,
+			AssetID: service.ToReversedString(types.GetSystemAssetId()),
+			Amount:  "1.5",
+		}},
+		LockTime: 100,
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	client := newTestClient(t, server, "pass")
+
+	req := testRequest()
+	data, err := client.CreateRawTransaction(req)
+	assert.NoError(t, err)
+	info, err := client.DecodeRawTransaction(data)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(100), info.LockTime)
+	assert.Equal(t, 1, len(info.Inputs))
+	assert.Equal(t, req.Inputs[0].TxID, info.Inputs[0].TxID)
+	assert.Equal(t, 1, len(info.Outputs))
+	assert.Equal(t, req.Outputs[0].Address, info.Outputs[0].Address)
+	assert.Equal(t, common.Fixed64(150000000).String(), info.Outputs[0].Value)
+
+	// Errors of the service are decoded.
+	_, err = client.GetNewAddress("password")
+	if assert.IsType(t, &Error{}, err) {
+		assert.Contains(t, err.(*Error).Message, "wallet is not enabled")
+	}
+	_, err = client.CreateRawTransaction(&sv.CreateRawTransactionRequest{})
+	if assert.IsType(t, &Error{}, err) {
+		assert.Contains(t, err.(*Error).Message, service.InvalidParams.String())
+	}
+	_, err = client.GetBlockCount()
+	assert.Error(t, err)
+}
+
+func TestClientRejected(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	_, err := newTestClient(t, server, "wrong").CreateRawTransaction(testRequest())
+	assert.Error(t, err)
+}
+
+func TestTransport(t *testing.T) {
+	// The white list of the node is usually "127.0.0.1", so "localhost" is
+	// connected in IPv4.
+	var remoteAddr string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+		w.Write([]byte(`{"id":1,"result":10,"error":null}`))
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NoError(t, err)
+
+	client, err := New(&Config{Address: "localhost:" + port})
+	assert.NoError(t, err)
+	count, err := client.GetBlockCount()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(10), count)
+	host, _, err := net.SplitHostPort(remoteAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+
+	_, err = New(&Config{Address: server.URL, LocalAddr: "localhost"})
+	assert.Error(t, err)
+}
+
+func TestBatch(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	client := newTestClient(t, server, "pass")
+
+	var data, address string
+	var info sv.TransactionInfo
+	batch := client.NewBatch()
+	create := batch.Queue("createrawtransaction", testRequest(), &data)
+	wallet := batch.Queue("getnewaddress", params{"password": "password"}, &address)
+	decode := batch.Queue("decoderawtransaction", params{"data": "00"}, &info)
+	assert.Equal(t, 3, batch.Len())
+	assert.NoError(t, batch.Send())
+
+	assert.NoError(t, create.Err)
+	assert.NotEmpty(t, data)
+	assert.IsType(t, &Error{}, wallet.Err)
+	assert.IsType(t, &Error{}, decode.Err)
+
+	// Batches fail as a whole if the client is not authorized.
+	batch = newTestClient(t, server, "wrong").NewBatch()
+	batch.Queue("createrawtransaction", testRequest(), &data)
+	assert.Error(t, batch.Send())
+}
+
+func TestBatchFallback(t *testing.T) {
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if bytes.HasPrefix(body, []byte("[")) {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"id":1,"result":10,"error":null}`))
+	}))
+	defer server.Close()
+
+	// Server errors are returned and batches are still sent later.
+	status = http.StatusBadGateway
+	client, err := New(&Config{Address: server.URL})
+	assert.NoError(t, err)
+	var count uint32
+	batch := client.NewBatch()
+	batch.Queue("getblockcount", nil, &count)
+	assert.Error(t, batch.Send())
+	assert.Equal(t, int32(0), client.noBatch)
+
+	// Batches are sent one by one if the node does not serve them.
+	status = http.StatusNotFound
+	call := batch.Queue("getblockcount", nil, &count)
+	assert.NoError(t, batch.Send())
+	assert.NoError(t, call.Err)
+	assert.Equal(t, uint32(10), count)
+	assert.Equal(t, int32(1), client.noBatch)
+}
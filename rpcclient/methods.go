@@ -0,0 +1,261 @@
+package rpcclient
+
+import (
+	sv "github.com/elastos/Elastos.ELA.SideChain.Token/service"
+)
+
+// defaultPageCount is the page size of the node if count is not given.
+const defaultPageCount = 100
+
+// Page is the position of a page, Cursor is empty for the first page and the
+// NextCursor of the previous page for the others.
+type Page struct {
+	Cursor string `json:"cursor"`
+	Count  uint32 `json:"count"`
+}
+
+func newPage(cursor string, count uint32) Page {
+	if count == 0 {
+		count = defaultPageCount
+	}
+	return Page{Cursor: cursor, Count: count}
+}
+
+type params map[string]interface{}
+
+func (c *Client) GetBlockCount() (uint32, error) {
+	var count uint32
+	err := c.Call("getblockcount", nil, &count)
+	return count, err
+}
+
+func (c *Client) GetCurrentHeight() (uint32, error) {
+	var height uint32
+	err := c.Call("getcurrentheight", nil, &height)
+	return height, err
+}
+
+func (c *Client) GetBestBlockHash() (string, error) {
+	var hash string
+	err := c.Call("getbestblockhash", nil, &hash)
+	return hash, err
+}
+
+func (c *Client) GetBlockHash(height uint32) (string, error) {
+	var hash string
+	err := c.Call("getblockhash", params{"height": height}, &hash)
+	return hash, err
+}
+
+func (c *Client) GetNodeState() (*sv.ServerInfo, error) {
+	var info sv.ServerInfo
+	if err := c.Call("getnodestate", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetRawTransaction returns the transaction in hex string.
+func (c *Client) GetRawTransaction(txID string) (string, error) {
+	var data string
+	err := c.Call("getrawtransaction", params{"txid": txID}, &data)
+	return data, err
+}
+
+// GetTransaction returns the info of the transaction in a block or the
+// transaction pool.
+func (c *Client) GetTransaction(txID string) (*sv.TransactionInfo, error) {
+	var info sv.TransactionInfo
+	if err := c.Call("getrawtransaction", params{"txid": txID, "verbose": true}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) DecodeRawTransaction(data string) (*sv.TransactionInfo, error) {
+	var info sv.TransactionInfo
+	if err := c.Call("decoderawtransaction", params{"data": data}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// CreateRawTransaction returns the unsigned transaction in hex string.
+func (c *Client) CreateRawTransaction(req *sv.CreateRawTransactionRequest) (string, error) {
+	var data string
+	err := c.Call("createrawtransaction", req, &data)
+	return data, err
+}
+
+// SendRawTransaction sends the signed transaction in hex string and returns
+// the transaction hash.
+func (c *Client) SendRawTransaction(data string) (string, error) {
+	var txID string
+	err := c.Call("sendrawtransaction", params{"data": data}, &txID)
+	return txID, err
+}
+
+func (c *Client) TestMempoolAccept(data string) (*sv.MempoolAcceptResult, error) {
+	var result sv.MempoolAcceptResult
+	if err := c.Call("testmempoolaccept", params{"data": data}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) ValidateRawTransaction(data string) (*sv.ValidationResult, error) {
+	var result sv.ValidationResult
+	if err := c.Call("validaterawtransaction", params{"data": data}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) GetMempoolInfo() (*sv.MempoolInfo, error) {
+	var info sv.MempoolInfo
+	if err := c.Call("getmempoolinfo", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) GetOutputsByMemo(memo string) ([]sv.OutputPoint, error) {
+	var outputs []sv.OutputPoint
+	err := c.Call("getoutputsbymemo", params{"memo": memo}, &outputs)
+	return outputs, err
+}
+
+func (c *Client) GetBlockFilter(blockHash string) (*sv.BlockFilter, error) {
+	var filter sv.BlockFilter
+	if err := c.Call("getblockfilter", params{"blockhash": blockHash}, &filter); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+func (c *Client) GetFilterHeaders(startHeight uint32, stopHash string) (*sv.FilterHeaders, error) {
+	var headers sv.FilterHeaders
+	err := c.Call("getfilterheaders", params{"startheight": startHeight, "stophash": stopHash}, &headers)
+	if err != nil {
+		return nil, err
+	}
+	return &headers, nil
+}
+
+// GetReceivedByAddress returns the balances of the address by asset id.
+func (c *Client) GetReceivedByAddress(address string) (map[string]string, error) {
+	var balances map[string]string
+	err := c.Call("getreceivedbyaddress", params{"address": address}, &balances)
+	return balances, err
+}
+
+// ListUnspent returns all the unspent outputs matching the request.
+func (c *Client) ListUnspent(req *sv.ListUnspentRequest) ([]sv.UTXOInfo, error) {
+	var utxos []sv.UTXOInfo
+	err := c.Call("listunspent", req, &utxos)
+	return utxos, err
+}
+
+// ListUnspentPage returns a page of the unspent outputs matching the request,
+// count is 100 if it is 0.
+func (c *Client) ListUnspentPage(req *sv.ListUnspentRequest, cursor string, count uint32) (*sv.UTXOPage, error) {
+	var page sv.UTXOPage
+	err := c.Call("listunspent", struct {
+		*sv.ListUnspentRequest
+		Page
+	}{req, newPage(cursor, count)}, &page)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (c *Client) GetAssetByHash(hash string) (*sv.AssetInfo, error) {
+	var info sv.AssetInfo
+	if err := c.Call("getassetbyhash", params{"hash": hash}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetAssetList returns all the assets matching the request, req can be nil.
+func (c *Client) GetAssetList(req *sv.AssetListRequest) ([]sv.AssetInfo, error) {
+	if req == nil {
+		req = &sv.AssetListRequest{}
+	}
+	var assets []sv.AssetInfo
+	err := c.Call("getassetlist", req, &assets)
+	return assets, err
+}
+
+// GetAssetPage returns a page of the assets matching the request, req can be
+// nil and count is 100 if it is 0.
+func (c *Client) GetAssetPage(req *sv.AssetListRequest, cursor string, count uint32) (*sv.AssetPage, error) {
+	if req == nil {
+		req = &sv.AssetListRequest{}
+	}
+	var page sv.AssetPage
+	err := c.Call("getassetlist", struct {
+		*sv.AssetListRequest
+		Page
+	}{req, newPage(cursor, count)}, &page)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (c *Client) GetAssetRegistrationFee(name string) (*sv.AssetRegistrationFee, error) {
+	var fee sv.AssetRegistrationFee
+	if err := c.Call("getassetregistrationfee", params{"name": name}, &fee); err != nil {
+		return nil, err
+	}
+	return &fee, nil
+}
+
+// GetTxOut returns the unspent output, or nil if the output is spent or not
+// found.
+func (c *Client) GetTxOut(txID string, vout uint16, includeMempool bool) (*sv.TxOutInfo, error) {
+	var info *sv.TxOutInfo
+	err := c.Call("gettxout", params{"txid": txID, "vout": vout, "includemempool": includeMempool}, &info)
+	return info, err
+}
+
+func (c *Client) GetTxOutSetInfo() (*sv.UTXOSetInfo, error) {
+	var info sv.UTXOSetInfo
+	if err := c.Call("gettxoutsetinfo", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) GetNewAddress(password string) (string, error) {
+	var address string
+	err := c.Call("getnewaddress", params{"password": password}, &address)
+	return address, err
+}
+
+func (c *Client) GetWalletBalance(minConf uint32) ([]sv.WalletBalance, error) {
+	var balances []sv.WalletBalance
+	err := c.Call("getwalletbalance", params{"minconf": minConf}, &balances)
+	return balances, err
+}
+
+// SendToken sends the tokens from the wallet and returns the transaction hash.
+func (c *Client) SendToken(req *sv.SendTokenRequest) (string, error) {
+	var txID string
+	err := c.Call("sendtoken", req, &txID)
+	return txID, err
+}
+
+// ListWalletTransactions returns the transactions of the wallet from the
+// newest, count is 10 if it is 0.
+func (c *Client) ListWalletTransactions(count, skip uint32) ([]sv.WalletTransaction, error) {
+	p := params{"skip": skip}
+	if count > 0 {
+		p["count"] = count
+	}
+	var txs []sv.WalletTransaction
+	err := c.Call("listwallettransactions", p, &txs)
+	return txs, err
+}
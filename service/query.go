@@ -0,0 +1,207 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+
+	. "github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/utils/http"
+)
+
+// Orders of the unspent outputs listed by listunspent.  SortByAddress is the
+// order of the unspent index, ascending pages of it are read from the index
+// directly, other orders load all the matching outputs to sort them.
+const (
+	SortByAddress       = "address"
+	SortByTxID          = "txid"
+	SortByAmount        = "amount"
+	SortByConfirmations = "confirmations"
+)
+
+// elaScale scales ELA values of 8 decimal places to token values of
+// TokenPrecision decimal places, so amounts of all assets are comparable.
+var elaScale = new(big.Int).Exp(big.NewInt(10), big.NewInt(core.TokenPrecision-8), nil)
+
+// pageParams returns the cursor and count of a paginated request, paged is
+// false if neither of them is given and all results should be returned.
+func pageParams(param http.Params) (cursor []byte, count int, paged bool, err error) {
+	str, hasCursor := param.String("cursor")
+	n, hasCount := param.Uint("count")
+	if !hasCursor && !hasCount {
+		return nil, 0, false, nil
+	}
+	if len(str) > 0 {
+		cursor, err = HexStringToBytes(str)
+		if err != nil {
+			return nil, 0, false, errors.New("invalid cursor " + str)
+		}
+	}
+	count = defaultPageLimit
+	if hasCount {
+		count = int(n)
+	}
+	if count <= 0 || count > maxPageLimit {
+		return nil, 0, false, fmt.Errorf("count must be between 1 and %d", maxPageLimit)
+	}
+	return cursor, count, true, nil
+}
+
+// parseAmount parses the amount parameter of whole tokens, the result is
+// scaled by 10^TokenPrecision, ok is false if the amount is not given.
+func parseAmount(value Amount, key string) (*big.Int, bool, error) {
+	if len(value) == 0 {
+		return nil, false, nil
+	}
+	amount, err := core.ParseTokenAmount(strings.TrimSpace(string(value)), core.TokenPrecision)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid %s: %s", key, err)
+	}
+	return amount.Int(), true, nil
+}
+
+// unspentEntry is an unspent output listed by listunspent.
+type unspentEntry struct {
+	key     []byte // the cursor of the output in the unspent index
+	address string
+	assetID Uint256
+	txID    Uint256
+	txIDStr string
+	index   uint32
+	height  uint32
+	value   *big.Int // scaled by 10^TokenPrecision
+	unspent *blockchain.UTXO
+}
+
+// scaledValue returns the value of the unspent output scaled by
+// 10^TokenPrecision.
+func scaledValue(assetID Uint256, value []byte) (*big.Int, error) {
+	if assetID.IsEqual(types.GetSystemAssetId()) {
+		number, err := Fixed64FromBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).Mul(big.NewInt(int64(*number)), elaScale), nil
+	}
+	amount, err := core.TokenAmountFromBytes(value)
+	if err != nil {
+		return nil, err
+	}
+	return amount.Int(), nil
+}
+
+// unspentOrder compares the unspent outputs in the sort order, ties are
+// broken by txid and vout, so the order is total and stable between pages.
+type unspentOrder struct {
+	sort string
+	desc bool
+}
+
+func newUnspentOrder(sort string, desc bool) (*unspentOrder, error) {
+	order := &unspentOrder{sort: SortByAddress, desc: desc}
+	switch sort {
+	case "":
+	case SortByAddress, SortByTxID, SortByAmount, SortByConfirmations:
+		order.sort = sort
+	default:
+		return nil, errors.New("unknown sort " + sort)
+	}
+	return order, nil
+}
+
+// indexed returns if the outputs are listed in the order of the unspent
+// index, so the pages are read from the index without sorting.
+func (o *unspentOrder) indexed() bool {
+	return o.sort == SortByAddress && !o.desc
+}
+
+func (o *unspentOrder) cmp(a, b *unspentEntry) int {
+	c := 0
+	switch o.sort {
+	case SortByAddress:
+		c = bytes.Compare(a.key, b.key)
+	case SortByAmount:
+		c = a.value.Cmp(b.value)
+	case SortByConfirmations:
+		// More confirmations are of lower heights.
+		switch {
+		case a.height > b.height:
+			c = -1
+		case a.height < b.height:
+			c = 1
+		}
+	}
+	if c == 0 {
+		c = strings.Compare(a.txIDStr, b.txIDStr)
+	}
+	if c == 0 {
+		switch {
+		case a.index < b.index:
+			c = -1
+		case a.index > b.index:
+			c = 1
+		}
+	}
+	if o.desc {
+		return -c
+	}
+	return c
+}
+
+// cursor encodes the sort keys of the entry as the cursor of the next page.
+func (o *unspentOrder) cursor(e *unspentEntry) string {
+	buf := new(bytes.Buffer)
+	WriteVarString(buf, o.sort)
+	var desc uint8
+	if o.desc {
+		desc = 1
+	}
+	WriteUint8(buf, desc)
+	WriteVarBytes(buf, e.key)
+	WriteUint32(buf, e.height)
+	WriteVarBytes(buf, e.value.Bytes())
+	e.txID.Serialize(buf)
+	WriteUint32(buf, e.index)
+	return BytesToHexString(buf.Bytes())
+}
+
+// parseCursor decodes the cursor into an entry with the sort keys.
+func (o *unspentOrder) parseCursor(cursor []byte) (*unspentEntry, error) {
+	r := bytes.NewReader(cursor)
+	sort, err := ReadVarString(r)
+	if err != nil || sort != o.sort {
+		return nil, errors.New("cursor does not match the sort")
+	}
+	desc, err := ReadUint8(r)
+	if err != nil || desc > 1 || (desc == 1) != o.desc {
+		return nil, errors.New("cursor does not match the sort")
+	}
+	e := new(unspentEntry)
+	if e.key, err = ReadVarBytes(r, blockchain.UnspentCursorSize, "key"); err != nil ||
+		len(e.key) != blockchain.UnspentCursorSize {
+		return nil, errors.New("invalid cursor")
+	}
+	if e.height, err = ReadUint32(r); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	value, err := ReadVarBytes(r, core.MaxTokenValueDataSize+8, "value")
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	e.value = new(big.Int).SetBytes(value)
+	if err := e.txID.Deserialize(r); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	if e.index, err = ReadUint32(r); err != nil || r.Len() != 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	e.txIDStr = service.ToReversedString(e.txID)
+	return e, nil
+}
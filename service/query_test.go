@@ -0,0 +1,142 @@
+package service
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/utils/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnspentOrderCursor(t *testing.T) {
+	entry := func(txID byte, index uint32, height uint32, value int64) *unspentEntry {
+		id := common.Uint256{txID}
+		key := make([]byte, blockchain.UnspentCursorSize)
+		key[0], key[blockchain.UnspentCursorSize-1] = txID, byte(index)
+		return &unspentEntry{
+			key:     key,
+			txID:    id,
+			txIDStr: service.ToReversedString(id),
+			index:   index,
+			height:  height,
+			value:   big.NewInt(value),
+		}
+	}
+	entries := []*unspentEntry{
+		entry(0x01, 0, 10, 500),
+		entry(0x01, 1, 10, 100),
+		entry(0x02, 0, 20, 500),
+		entry(0x03, 0, 5, 300),
+		entry(0x04, 2, 20, 100),
+	}
+
+	for _, sortBy := range []string{SortByAddress, SortByTxID, SortByAmount, SortByConfirmations} {
+		for _, desc := range []bool{false, true} {
+			order, err := newUnspentOrder(sortBy, desc)
+			assert.NoError(t, err)
+
+			all := append([]*unspentEntry{}, entries...)
+			sort.Slice(all, func(i, j int) bool { return order.cmp(all[i], all[j]) < 0 })
+
+			// Page through the entries two by two.
+			var paged []*unspentEntry
+			var after *unspentEntry
+			for {
+				var page []*unspentEntry
+				for _, e := range all {
+					if after == nil || order.cmp(after, e) < 0 {
+						page = append(page, e)
+					}
+				}
+				if len(page) > 2 {
+					page = page[:2]
+				}
+				paged = append(paged, page...)
+				if len(paged) == len(all) {
+					break
+				}
+
+				cursor, err := common.HexStringToBytes(order.cursor(page[len(page)-1]))
+				assert.NoError(t, err)
+				after, err = order.parseCursor(cursor)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, all, paged)
+		}
+	}
+
+	order, _ := newUnspentOrder(SortByAmount, true)
+	all := append([]*unspentEntry{}, entries...)
+	sort.Slice(all, func(i, j int) bool { return order.cmp(all[i], all[j]) < 0 })
+	assert.Equal(t, []int64{500, 500, 300, 100, 100}, []int64{
+		all[0].value.Int64(), all[1].value.Int64(), all[2].value.Int64(),
+		all[3].value.Int64(), all[4].value.Int64()})
+
+	// A cursor of another sort is rejected.
+	cursor, _ := common.HexStringToBytes(order.cursor(entries[0]))
+	other, _ := newUnspentOrder(SortByTxID, false)
+	_, err := other.parseCursor(cursor)
+	assert.Error(t, err)
+	_, err = order.parseCursor(cursor[:len(cursor)-1])
+	assert.Error(t, err)
+
+	// A cursor of the other direction is rejected.
+	asc, _ := newUnspentOrder(SortByAmount, false)
+	_, err = asc.parseCursor(cursor)
+	assert.Error(t, err)
+
+	order, _ = newUnspentOrder("", false)
+	assert.Equal(t, SortByAddress, order.sort)
+	assert.True(t, order.indexed())
+
+	_, err = newUnspentOrder("height", false)
+	assert.Error(t, err)
+}
+
+func TestPageParams(t *testing.T) {
+	_, _, paged, err := pageParams(http.Params{})
+	assert.NoError(t, err)
+	assert.False(t, paged)
+
+	_, count, paged, err := pageParams(http.Params{"cursor": ""})
+	assert.NoError(t, err)
+	assert.True(t, paged)
+	assert.Equal(t, defaultPageLimit, count)
+
+	cursor, count, _, err := pageParams(http.Params{"cursor": "0a0b", "count": float64(10)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x0a, 0x0b}, cursor)
+	assert.Equal(t, 10, count)
+
+	_, _, _, err = pageParams(http.Params{"count": float64(maxPageLimit + 1)})
+	assert.Error(t, err)
+	_, _, _, err = pageParams(http.Params{"cursor": "xyz"})
+	assert.Error(t, err)
+}
+
+func TestParseAmount(t *testing.T) {
+	amount, ok, err := parseAmount("0.5", "minamount")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "500000000000000000", amount.String())
+
+	// Amounts are given in decimal strings or numbers.
+	var req ListUnspentRequest
+	assert.True(t, decodeParams(http.Params{"minamount": float64(2)}, &req))
+	amount, _, err = parseAmount(req.MinAmount, "minamount")
+	assert.NoError(t, err)
+	assert.Equal(t, "2000000000000000000", amount.String())
+	assert.False(t, decodeParams(http.Params{"minamount": true}, &req))
+
+	_, ok, err = parseAmount("", "minamount")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = parseAmount("1.0000000000000000001", "minamount")
+	assert.Error(t, err)
+}
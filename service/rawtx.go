@@ -0,0 +1,307 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/coinselect"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+
+	. "github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/utils/http"
+)
+
+// PrecisionFunc returns the precision of the asset, ok is false if the asset
+// is unknown.
+type PrecisionFunc func(assetID Uint256) (precision byte, ok bool)
+
+// StorePrecision returns the PrecisionFunc looking up the assets registered in
+// the store.
+func StorePrecision(store *blockchain.TokenChainStore) PrecisionFunc {
+	return func(assetID Uint256) (byte, bool) {
+		asset, err := store.GetAsset(assetID)
+		if err != nil {
+			return 0, false
+		}
+		return asset.Precision, true
+	}
+}
+
+// ParseHash parses a hash in reversed hex string.
+func ParseHash(str string) (Uint256, error) {
+	hashBytes, err := service.FromReversedString(str)
+	if err != nil {
+		return Uint256{}, err
+	}
+	hash, err := Uint256FromBytes(hashBytes)
+	if err != nil {
+		return Uint256{}, err
+	}
+	return *hash, nil
+}
+
+// DecodeTransaction returns the info of the transaction which may not be known
+// by the node, token amounts are formatted with the precisions of their assets
+// if known.
+func DecodeTransaction(cfg *service.Config, tx *types.Transaction, precision PrecisionFunc,
+	withMemos bool) *TransactionInfo {
+	info := GetTokenTransactionInfo(cfg, nil, tx, withMemos)
+	for i, output := range tx.Outputs {
+		if output.AssetID.IsEqual(types.GetSystemAssetId()) {
+			continue
+		}
+		if p, ok := precision(output.AssetID); ok {
+			info.Outputs[i].Value = core.NewTokenAmount(&output.TokenValue).Format(p)
+		}
+	}
+	return info
+}
+
+// CreateRawTransaction creates an unsigned transfer transaction spending the
+// inputs to the outputs, token amounts are parsed with the precisions of their
+// assets.
+func CreateRawTransaction(inputs []RawTxInput, outputs []RawTxOutput, lockTime uint32,
+	precision PrecisionFunc) (*types.Transaction, error) {
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return nil, errors.New("inputs and outputs can not be empty")
+	}
+
+	txInputs := make([]*types.Input, 0, len(inputs))
+	for _, input := range inputs {
+		txID, err := ParseHash(input.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input txid %s", input.TxID)
+		}
+		txInputs = append(txInputs, &types.Input{
+			Previous: types.OutPoint{TxID: txID, Index: input.VOut},
+			Sequence: input.Sequence,
+		})
+	}
+
+	txOutputs := make([]*types.Output, 0, len(outputs))
+	memos := make(map[int][]byte)
+	for i, o := range outputs {
+		output, memo, err := createOutput(o, precision)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output %d, %s", i, err)
+		}
+		txOutputs = append(txOutputs, output)
+		if len(memo) > 0 {
+			memos[i] = memo
+		}
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	tx := &types.Transaction{
+		TxType:     types.TransferAsset,
+		Payload:    &types.PayloadTransferAsset{},
+		Attributes: []*types.Attribute{{Usage: types.Nonce, Data: nonce}},
+		Inputs:     txInputs,
+		Outputs:    txOutputs,
+		LockTime:   lockTime,
+		Programs:   []*types.Program{},
+	}
+	if err := core.SetOutputMemos(tx, memos); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// createOutput returns the output and the memo of it.
+func createOutput(o RawTxOutput, precision PrecisionFunc) (*types.Output, []byte, error) {
+	programHash, err := Uint168FromAddress(o.Address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid address %s", o.Address)
+	}
+	assetID, err := ParseHash(o.AssetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid asset id %s", o.AssetID)
+	}
+
+	output := &types.Output{
+		AssetID:     assetID,
+		OutputLock:  o.OutputLock,
+		ProgramHash: *programHash,
+	}
+	if assetID.IsEqual(types.GetSystemAssetId()) {
+		if len(o.Memo) > 0 {
+			return nil, nil, errors.New("memo is not supported by ELA output")
+		}
+		value, err := StringToFixed64(o.Amount)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid amount %s", o.Amount)
+		}
+		output.Value = *value
+		return output, nil, nil
+	}
+
+	p, ok := precision(assetID)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown precision of asset %s", o.AssetID)
+	}
+	amount, err := core.ParseTokenAmount(o.Amount, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	output.TokenValue.Set(amount.Int())
+
+	if len(o.Memo) > core.MaxOutputMemoSize {
+		return nil, nil, fmt.Errorf("memo exceeds %d bytes", core.MaxOutputMemoSize)
+	}
+	return output, []byte(o.Memo), nil
+}
+
+// assetPrecision returns the precision of assets registered in blockchain.
+func (s *HttpService) assetPrecision(assetID Uint256) (byte, bool) {
+	return StorePrecision(s.store)(assetID)
+}
+
+// decodeParams decodes the parameters into the request v through JSON, so the
+// parameters are named by the JSON tags of the request shared with clients.
+func decodeParams(param http.Params, v interface{}) bool {
+	data, err := json.Marshal(param)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+func (s *HttpService) DecodeRawTransaction(param http.Params) (interface{}, error) {
+	str, ok := param.String("data")
+	if !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	data, err := HexStringToBytes(str)
+	if err != nil {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	var tx types.Transaction
+	if err := tx.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, errors.New("invalid transaction: " + err.Error())
+	}
+	info := DecodeTransaction(&s.cfg.Config, &tx, s.assetPrecision, s.memoActive(nil))
+	if raw, _ := param.Bool("rawunits"); raw {
+		rawUnits(info, &tx)
+	}
+	return info, nil
+}
+
+func (s *HttpService) CreateRawTransaction(param http.Params) (interface{}, error) {
+	var req CreateRawTransactionRequest
+	if !decodeParams(param, &req) || req.Outputs == nil {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+
+	var tx *types.Transaction
+	var err error
+	if len(req.FromAddresses) > 0 {
+		if len(req.Inputs) > 0 {
+			return nil, errors.New("inputs and fromaddresses can not be both given")
+		}
+		tx, err = s.fundTransaction(&req)
+		if err == nil && req.LockTime > tx.LockTime {
+			tx.LockTime = req.LockTime
+		}
+	} else if req.Inputs != nil {
+		tx, err = CreateRawTransaction(req.Inputs, req.Outputs, req.LockTime, s.assetPrecision)
+	} else {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := tx.Serialize(buf); err != nil {
+		return nil, err
+	}
+	return BytesToHexString(buf.Bytes()), nil
+}
+
+// fundTransaction creates an unsigned transfer transaction paying the outputs
+// from the spendable outputs of the from addresses, the inputs are selected by
+// the strategy and the changes are paid to the change address, or the first
+// address if it is not given.
+func (s *HttpService) fundTransaction(r *CreateRawTransactionRequest) (*types.Transaction, error) {
+	programHashes := make([]Uint168, 0, len(r.FromAddresses))
+	for _, address := range r.FromAddresses {
+		programHash, err := Uint168FromAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %s", address)
+		}
+		programHashes = append(programHashes, *programHash)
+	}
+
+	req := &coinselect.Request{
+		Change:  programHashes[0],
+		FeeRate: s.cfg.TxPool.Info().MinFeeRate,
+	}
+	for i, o := range r.Outputs {
+		output, memo, err := createOutput(o, s.assetPrecision)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output %d, %s", i, err)
+		}
+		req.Outputs = append(req.Outputs, output)
+		if len(memo) > 0 {
+			if req.Memos == nil {
+				req.Memos = make(map[int][]byte)
+			}
+			req.Memos[i] = memo
+		}
+	}
+	if len(r.ChangeAddress) > 0 {
+		programHash, err := Uint168FromAddress(r.ChangeAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid change address %s", r.ChangeAddress)
+		}
+		req.Change = *programHash
+	}
+	if len(r.Fee) > 0 {
+		fee, err := StringToFixed64(r.Fee)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee %s", r.Fee)
+		}
+		req.MinFee = *fee
+	}
+	if len(r.Strategy) > 0 {
+		strategy, err := coinselect.ParseStrategy(r.Strategy)
+		if err != nil {
+			return nil, err
+		}
+		req.Strategy = strategy
+	}
+
+	spent := make(map[types.OutPoint]struct{})
+	for _, tx := range s.cfg.TxPool.GetTxsInPool() {
+		for _, input := range tx.Inputs {
+			spent[input.Previous] = struct{}{}
+		}
+	}
+	var coins []*coinselect.Coin
+	for _, programHash := range programHashes {
+		unspents, err := s.cfg.Selector.Coins(programHash)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range unspents {
+			if _, ok := spent[c.OutPoint]; !ok {
+				coins = append(coins, c)
+			}
+		}
+	}
+
+	result, err := s.cfg.Selector.Fund(coins, req)
+	if err != nil {
+		return nil, err
+	}
+	return result.Transaction()
+}
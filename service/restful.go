@@ -0,0 +1,344 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+
+	. "github.com/elastos/Elastos.ELA/common"
+	elahttp "github.com/elastos/Elastos.ELA/utils/http"
+)
+
+const (
+	// restAPIPrefix is the path prefix of all RESTful resources.
+	restAPIPrefix = "/api/v1"
+
+	// defaultPageLimit and maxPageLimit are the default and maximum number of
+	// items in a page of list resources.
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// stringQueries are the query parameters always kept as strings, since hex
+// strings may look like numbers.
+var stringQueries = map[string]bool{
+	"assetid":   true,
+	"cursor":    true,
+	"minamount": true,
+	"maxamount": true,
+}
+
+// restAction handles a RESTful resource with the path and query parameters.
+type restAction func(param elahttp.Params) (interface{}, error)
+
+// restRoute maps a resource path pattern to an action.  Segments start with
+// ":" in the pattern are path parameters, and the numeric ones are converted
+// to numbers like JSON-RPC parameters.
+type restRoute struct {
+	segments []string
+	numeric  map[string]bool
+	list     bool
+	action   restAction
+}
+
+// match returns the path parameters if the path matches the route.
+func (r *restRoute) match(segments []string) (elahttp.Params, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	param := make(elahttp.Params)
+	for i, segment := range r.segments {
+		if !strings.HasPrefix(segment, ":") {
+			if segment != segments[i] {
+				return nil, false
+			}
+			continue
+		}
+		name := segment[1:]
+		if r.numeric[name] {
+			n, err := strconv.ParseUint(segments[i], 10, 32)
+			if err != nil {
+				return nil, false
+			}
+			param[name] = float64(n)
+		} else {
+			param[name] = segments[i]
+		}
+	}
+	return param, true
+}
+
+// RESTResponse is the response of RESTful resources, Total, Offset and Limit
+// are set for list resources.
+type RESTResponse struct {
+	Result interface{} `json:"result"`
+	Error  string      `json:"error,omitempty"`
+	Total  *int        `json:"total,omitempty"`
+	Offset int         `json:"offset,omitempty"`
+	Limit  int         `json:"limit,omitempty"`
+}
+
+// RESTServer serves the queries of HttpService as RESTful resources.
+// RESTConfig is the configuration of the RESTful server, User, Pass and
+// WhiteList are checked the same as the JSON-RPC server.
+type RESTConfig struct {
+	ServePort uint16
+	User      string
+	Pass      string
+	WhiteList []string
+}
+
+type RESTServer struct {
+	cfg     *RESTConfig
+	service *HttpService
+	routes  []*restRoute
+	server  *http.Server
+}
+
+// route registers the action of the resource path pattern, list resources
+// are paginated by the offset and limit query parameters.
+func (s *RESTServer) route(pattern string, list bool, action restAction, numeric ...string) {
+	route := &restRoute{
+		segments: strings.Split(strings.Trim(pattern, "/"), "/"),
+		numeric:  make(map[string]bool),
+		list:     list,
+		action:   action,
+	}
+	for _, name := range numeric {
+		route.numeric[name] = true
+	}
+	s.routes = append(s.routes, route)
+}
+
+// clientAllowed returns if the client is in the white list, clients on the
+// loopback address are always allowed.
+func (s *RESTServer) clientAllowed(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	for _, allowed := range s.cfg.WhiteList {
+		if allowed == "0.0.0.0" || allowed == ip.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAuth returns if the request carries the basic authorization of the
+// configured user and password, no authorization is required if neither is
+// configured.
+func (s *RESTServer) checkAuth(r *http.Request) bool {
+	if len(s.cfg.User) == 0 && len(s.cfg.Pass) == 0 {
+		return true
+	}
+	login := s.cfg.User + ":" + s.cfg.Pass
+	auth := sha256.Sum256([]byte("Basic " + base64.StdEncoding.EncodeToString([]byte(login))))
+	given := sha256.Sum256([]byte(r.Header.Get("Authorization")))
+	return subtle.ConstantTimeCompare(given[:], auth[:]) == 1
+}
+
+func (s *RESTServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.clientAllowed(r) {
+		writeREST(w, http.StatusForbidden, RESTResponse{Error: "client ip is not allowed"})
+		return
+	}
+	if !s.checkAuth(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="token node"`)
+		writeREST(w, http.StatusUnauthorized, RESTResponse{Error: "unauthorized"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeREST(w, http.StatusMethodNotAllowed, RESTResponse{Error: "method not allowed"})
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, restAPIPrefix+"/") {
+		writeREST(w, http.StatusNotFound, RESTResponse{Error: "resource not found"})
+		return
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, restAPIPrefix), "/"), "/")
+
+	for _, route := range s.routes {
+		param, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+		for key, values := range r.URL.Query() {
+			if _, ok := param[key]; ok || len(values) == 0 {
+				continue
+			}
+			if stringQueries[key] {
+				param[key] = values[0]
+			} else {
+				param[key] = queryValue(values[0])
+			}
+		}
+
+		result, err := route.action(param)
+		if err != nil {
+			writeREST(w, http.StatusBadRequest, RESTResponse{Error: err.Error()})
+			return
+		}
+		if !route.list {
+			writeREST(w, http.StatusOK, RESTResponse{Result: result})
+			return
+		}
+		resp, err := paginate(result, param)
+		if err != nil {
+			writeREST(w, http.StatusBadRequest, RESTResponse{Error: err.Error()})
+			return
+		}
+		writeREST(w, http.StatusOK, resp)
+		return
+	}
+	writeREST(w, http.StatusNotFound, RESTResponse{Error: "resource not found"})
+}
+
+// queryValue converts the query value to the type JSON-RPC parameters are
+// decoded into, so the HttpService methods can read them.
+func queryValue(value string) interface{} {
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil && !strings.ContainsAny(value, "eE") {
+		return n
+	}
+	return value
+}
+
+// paginate returns the page of the list result by the offset and limit
+// parameters.
+func paginate(result interface{}, param elahttp.Params) (RESTResponse, error) {
+	offset, limit := 0, defaultPageLimit
+	if v, ok := param.Uint("offset"); ok {
+		offset = int(v)
+	}
+	if v, ok := param.Uint("limit"); ok {
+		limit = int(v)
+	}
+	if limit <= 0 || limit > maxPageLimit {
+		return RESTResponse{}, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+	}
+
+	// Results paged by cursor are returned as they are.
+	items := reflect.ValueOf(result)
+	if items.Kind() != reflect.Slice {
+		return RESTResponse{Result: result}, nil
+	}
+	total := items.Len()
+	start, end := offset, offset+limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+	return RESTResponse{
+		Result: items.Slice(start, end).Interface(),
+		Total:  &total,
+		Offset: offset,
+		Limit:  limit,
+	}, nil
+}
+
+func writeREST(w http.ResponseWriter, status int, resp RESTResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// listMempool returns the transactions in pool sorted by hash.
+func (s *RESTServer) listMempool(param elahttp.Params) (interface{}, error) {
+	txs := s.service.cfg.TxPool.GetTxsInPool()
+	hashes := make([]Uint256, 0, len(txs))
+	for hash := range txs {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return service.ToReversedString(hashes[i]) < service.ToReversedString(hashes[j])
+	})
+
+	raw, _ := param.Bool("rawunits")
+	infos := make([]*TransactionInfo, 0, len(hashes))
+	for _, hash := range hashes {
+		info := GetTokenTransactionInfo(&s.service.cfg.Config, nil, txs[hash], s.service.memoActive(nil))
+		if raw {
+			rawUnits(info, txs[hash])
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// addressUTXOs lists the unspent outputs of the address in path.
+func (s *RESTServer) addressUTXOs(param elahttp.Params) (interface{}, error) {
+	address, ok := param.String("address")
+	if !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	param["addresses"] = []interface{}{address}
+	return s.service.ListUnspent(param)
+}
+
+// transaction returns the verbose info of the transaction.
+func (s *RESTServer) transaction(param elahttp.Params) (interface{}, error) {
+	param["verbose"] = true
+	return s.service.GetRawTransaction(param)
+}
+
+// Start starts serving RESTful resources, it blocks until the server stopped.
+func (s *RESTServer) Start() error {
+	listener, err := net.Listen("tcp", fmt.Sprint(":", s.cfg.ServePort))
+	if err != nil {
+		return err
+	}
+	err = s.server.Serve(listener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Stop stops the RESTful server.
+func (s *RESTServer) Stop() error {
+	return s.server.Close()
+}
+
+func NewRESTServer(cfg *RESTConfig, hs *HttpService) *RESTServer {
+	s := &RESTServer{cfg: cfg, service: hs}
+	s.server = &http.Server{Handler: s}
+
+	s.route("/block/count", false, hs.GetBlockCount)
+	s.route("/block/best", false, hs.GetBestBlockHash)
+	s.route("/block/height/:height", false, hs.GetBlockByHeight, "height")
+	s.route("/block/hash/:blockhash", false, hs.GetBlockByHash)
+	s.route("/transaction/:txid", false, s.transaction)
+	s.route("/assets", true, hs.GetAssetList)
+	s.route("/asset/:hash", false, hs.GetAssetByHash)
+	s.route("/address/:address/balance", false, hs.GetReceivedByAddress)
+	s.route("/address/:address/utxos", true, s.addressUTXOs)
+	s.route("/mempool", true, s.listMempool)
+	s.route("/mempool/info", false, hs.GetMempoolInfo)
+	s.route("/txout/:txid/:vout", false, hs.GetTxOut, "vout")
+	s.route("/utxoset", false, hs.GetTxOutSetInfo)
+
+	return s
+}
@@ -6,8 +6,19 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"sort"
+	"strconv"
+	"unicode/utf8"
 
 	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/coinselect"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	mp "github.com/elastos/Elastos.ELA.SideChain.Token/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/p2p"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/p2p/msg"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/wallet"
+	"github.com/elastos/Elastos.ELA.SideChain/mempool"
 	"github.com/elastos/Elastos.ELA.SideChain/service"
 	"github.com/elastos/Elastos.ELA.SideChain/types"
 
@@ -18,10 +29,17 @@ import (
 
 type Config struct {
 	service.Config
-	Compile  string
-	NodePort uint16
-	RPCPort  uint16
-	Store    *blockchain.TokenChainStore
+	Compile     string
+	NodePort    uint16
+	RPCPort     uint16
+	Store       *blockchain.TokenChainStore
+	AssetParams *params.AssetParams
+	TxPool      *mp.TxPool
+	Validator   *mempool.Validator
+	Diagnoser   *mp.Diagnoser
+	CFilters    *p2p.CFilterService
+	Selector    *coinselect.Selector
+	Wallet      *wallet.Wallet
 }
 
 type HttpService struct {
@@ -97,7 +115,15 @@ func GetPayloadInfo(p types.Payload, pVersion byte) service.PayloadInfo {
 		}
 		return obj
 	case *types.PayloadTransferAsset:
+		return new(TransferAssetInfo)
 	case *types.PayloadRecord:
+		obj := new(RecordInfo)
+		obj.RecordType = object.RecordType
+		obj.RecordData = BytesToHexString(object.RecordData)
+		if utf8.Valid(object.RecordData) {
+			obj.Text = string(object.RecordData)
+		}
+		return obj
 	case *types.PayloadRechargeToSideChain:
 		if pVersion == types.RechargeToSideChainPayloadVersion0 {
 			obj := new(service.RechargeToSideChainInfoV0)
@@ -109,11 +135,66 @@ func GetPayloadInfo(p types.Payload, pVersion byte) service.PayloadInfo {
 			obj.MainChainTransactionHash = service.ToReversedString(object.MainChainTransactionHash)
 			return obj
 		}
+		return &UnknownPayloadInfo{
+			Version: pVersion,
+			Error:   fmt.Sprintf("unknown recharge to side chain payload version %d", pVersion),
+		}
+	case nil:
+		return nil
+	}
+	return &UnknownPayloadInfo{
+		Version: pVersion,
+		Error:   fmt.Sprintf("unknown payload type %T", p),
 	}
-	return nil
 }
 
+// GetTransactionInfo returns the transaction info, token values are rendered
+// in all the TokenPrecision decimal places.
 func GetTransactionInfo(cfg *service.Config, header *types.Header, tx *types.Transaction) *service.TransactionInfo {
+	return transactionInfo(cfg, header, tx, nil)
+}
+
+// TransactionInfoFunc returns the GetTransactionInfo function of the side
+// chain service config, token values are rendered in the precisions of their
+// assets.
+func TransactionInfoFunc(precision PrecisionFunc) func(*service.Config, *types.Header, *types.Transaction) *service.TransactionInfo {
+	return func(cfg *service.Config, header *types.Header, tx *types.Transaction) *service.TransactionInfo {
+		return transactionInfo(cfg, header, tx, precision)
+	}
+}
+
+// outputValue returns the value of the output as an exact decimal string,
+// token values are rendered in the precision of the asset if it is known.
+func outputValue(output *types.Output, precision PrecisionFunc) string {
+	if output.AssetID.IsEqual(types.GetSystemAssetId()) {
+		return output.Value.String()
+	}
+	amount := core.NewTokenAmount(&output.TokenValue)
+	if precision != nil {
+		if p, ok := precision(output.AssetID); ok {
+			return amount.Format(p)
+		}
+	}
+	return amount.String()
+}
+
+// outputUnits returns the value of the output in base units.
+func outputUnits(output *types.Output) string {
+	if output.AssetID.IsEqual(types.GetSystemAssetId()) {
+		return strconv.FormatInt(int64(output.Value), 10)
+	}
+	return output.TokenValue.String()
+}
+
+// rawUnits renders the output values of the transaction info in base units.
+func rawUnits(info *TransactionInfo, tx *types.Transaction) {
+	for i, output := range tx.Outputs {
+		info.Outputs[i].Value = outputUnits(output)
+	}
+}
+
+func transactionInfo(cfg *service.Config, header *types.Header, tx *types.Transaction,
+	precision PrecisionFunc) *service.TransactionInfo {
 	inputs := make([]service.InputInfo, len(tx.Inputs))
 	for i, v := range tx.Inputs {
 		inputs[i].TxID = service.ToReversedString(v.Previous.TxID)
@@ -123,11 +204,7 @@ func GetTransactionInfo(cfg *service.Config, header *types.Header, tx *types.Tra
 
 	outputs := make([]service.OutputInfo, len(tx.Outputs))
 	for i, v := range tx.Outputs {
-		if v.AssetID.IsEqual(types.GetSystemAssetId()) {
-			outputs[i].Value = v.Value.String()
-		} else {
-			outputs[i].Value = new(big.Int).Div(&v.TokenValue, big.NewInt(int64(math.Pow10(18)))).String()
-		}
+		outputs[i].Value = outputValue(v, precision)
 		outputs[i].Index = uint32(i)
 		address, _ := v.ProgramHash.ToAddress()
 		outputs[i].Address = address
@@ -182,8 +259,99 @@ func GetTransactionInfo(cfg *service.Config, header *types.Header, tx *types.Tra
 	}
 }
 
+// GetTokenTransactionInfo returns the transaction info, with output memos if
+// withMemos is set.  Memo attributes are not output memos before output memos
+// are activated, so they are only rendered at the heights memos are active.
+func GetTokenTransactionInfo(cfg *service.Config, header *types.Header, tx *types.Transaction,
+	withMemos bool) *TransactionInfo {
+	info := cfg.GetTransactionInfo(cfg, header, tx)
+	var memos map[int][]byte
+	if withMemos {
+		memos, _ = core.OutputMemos(tx)
+	}
+	outputs := make([]OutputInfo, len(info.Outputs))
+	for i, output := range info.Outputs {
+		outputs[i].OutputInfo = output
+		outputs[i].Memo = string(memos[i])
+	}
+	return &TransactionInfo{TransactionInfo: info, Outputs: outputs}
+}
+
+// GetRawTransaction overrides the side chain method, so output memos are
+// rendered in the verbose result.
+func (s *HttpService) GetRawTransaction(param http.Params) (interface{}, error) {
+	hash, ok := hashParam(param, "txid")
+	if !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+
+	var header *types.Header
+	tx, height, err := s.store.GetTransaction(hash)
+	if err != nil {
+		tx = s.cfg.TxPool.GetTransaction(hash)
+		if tx == nil {
+			return nil, errors.New(service.UnknownTransaction.String())
+		}
+	} else {
+		blockHash, err := s.store.GetBlockHash(height)
+		if err != nil {
+			return nil, errors.New(service.UnknownBlock.String())
+		}
+		header, err = s.store.GetHeader(blockHash)
+		if err != nil {
+			return nil, errors.New(service.UnknownBlock.String())
+		}
+	}
+
+	verbose, _ := param.Bool("verbose")
+	if verbose {
+		info := GetTokenTransactionInfo(&s.cfg.Config, header, tx, s.memoActive(header))
+		if raw, _ := param.Bool("rawunits"); raw {
+			rawUnits(info, tx)
+		}
+		return info, nil
+	}
+	buf := new(bytes.Buffer)
+	if err := tx.Serialize(buf); err != nil {
+		return nil, err
+	}
+	return BytesToHexString(buf.Bytes()), nil
+}
+
+// memoActive returns if output memos are active at the height of the block
+// with the given header, or the next block if header is nil.
+func (s *HttpService) memoActive(header *types.Header) bool {
+	height := s.store.GetHeight() + 1
+	if header != nil {
+		height = header.Height
+	}
+	return s.cfg.AssetParams.IsOutputMemoActive(height)
+}
+
+func (s *HttpService) GetOutputsByMemo(param http.Params) (interface{}, error) {
+	memo, ok := param.String("memo")
+	if !ok || len(memo) == 0 || len(memo) > core.MaxOutputMemoSize {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	if !s.memoActive(nil) {
+		return []OutputPoint{}, nil
+	}
+	outpoints, err := s.store.GetOutputsByMemo([]byte(memo))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]OutputPoint, 0, len(outpoints))
+	for _, outpoint := range outpoints {
+		result = append(result, OutputPoint{
+			TxID: service.ToReversedString(outpoint.TxID),
+			VOut: outpoint.Index,
+		})
+	}
+	return result, nil
+}
+
 func (s *HttpService) GetReceivedByAddress(param http.Params) (interface{}, error) {
-	tokenValueList := make(map[Uint256]*big.Int)
+	tokenValueList := make(map[Uint256]*core.TokenAmount)
 	var elaValue Fixed64
 	str, ok := param.String("address")
 	if !ok {
@@ -201,20 +369,33 @@ func (s *HttpService) GetReceivedByAddress(param http.Params) (interface{}, erro
 				value, _ := Fixed64FromBytes(u.Value)
 				elaValue += *value
 			} else {
-				value := new(big.Int).SetBytes(u.Value)
+				value, err := core.TokenAmountFromBytes(u.Value)
+				if err != nil {
+					return nil, err
+				}
 				if _, ok := tokenValueList[assetID]; !ok {
-					tokenValueList[assetID] = new(big.Int)
+					tokenValueList[assetID] = new(core.TokenAmount)
 				}
-				tokenValueList[assetID] = tokenValueList[assetID].Add(tokenValueList[assetID], value)
+				tokenValueList[assetID].Add(value)
 			}
 		}
 	}
+	raw, _ := param.Bool("rawunits")
 	valueList := make(map[string]string)
-	valueList[BytesToHexString(BytesReverse(types.GetSystemAssetId().Bytes()))] = elaValue.String()
+	if raw {
+		valueList[BytesToHexString(BytesReverse(types.GetSystemAssetId().Bytes()))] = strconv.FormatInt(int64(elaValue), 10)
+	} else {
+		valueList[BytesToHexString(BytesReverse(types.GetSystemAssetId().Bytes()))] = elaValue.String()
+	}
 	for k, v := range tokenValueList {
 		reverse, _ := Uint256FromBytes(BytesReverse(k.Bytes()))
-		totalValue, _ := new(big.Int).SetString(v.String(), 10)
-		valueList[reverse.String()] = totalValue.Div(totalValue, big.NewInt(int64(math.Pow10(18)))).String()
+		if raw {
+			valueList[reverse.String()] = v.Int().String()
+		} else if precision, ok := s.assetPrecision(k); ok {
+			valueList[reverse.String()] = v.Format(precision)
+		} else {
+			valueList[reverse.String()] = v.String()
+		}
 	}
 	if assetID, ok := param.String("assetid"); ok {
 		return map[string]string{assetID: valueList[assetID]}, nil
@@ -225,72 +406,172 @@ func (s *HttpService) GetReceivedByAddress(param http.Params) (interface{}, erro
 
 func (s *HttpService) ListUnspent(param http.Params) (interface{}, error) {
 	bestHeight := s.store.GetHeight()
-	type UTXOInfo struct {
-		AssetId       string `json:"assetid"`
-		Txid          string `json:"txid"`
-		VOut          uint32 `json:"vout"`
-		Address       string `json:"address"`
-		Amount        string `json:"amount"`
-		Confirmations uint32 `json:"confirmations"`
-		OutputLock    uint32 `json:"outputlock"`
+	var req ListUnspentRequest
+	if !decodeParams(param, &req) {
+		return nil, errors.New(service.InvalidParams.String())
 	}
-
-	var allResults, results []UTXOInfo
-
-	if _, ok := param["addresses"]; !ok {
+	if req.Addresses == nil {
 		return nil, errors.New("need a param called address")
 	}
-	var addressStrings []string
-	switch addresses := param["addresses"].(type) {
-	case []interface{}:
-		for _, v := range addresses {
-			str, ok := v.(string)
-			if !ok {
-				return nil, errors.New("please send a string")
-			}
-			addressStrings = append(addressStrings, str)
+	raw := req.RawUnits
+
+	cursor, count, paged, err := pageParams(param)
+	if err != nil {
+		return nil, err
+	}
+	order, err := newUnspentOrder(req.Sort, req.Desc)
+	if err != nil {
+		return nil, err
+	}
+	var after *unspentEntry
+	if len(cursor) > 0 {
+		if after, err = order.parseCursor(cursor); err != nil {
+			return nil, err
 		}
-	default:
-		return nil, errors.New("wrong type")
+	}
+	var assetID *Uint256
+	if len(req.AssetID) > 0 {
+		id, err := ParseHash(req.AssetID)
+		if err != nil {
+			return nil, errors.New("invalid assetid " + req.AssetID)
+		}
+		assetID = &id
+	}
+	minConf := req.MinConf
+	minAmount, hasMin, err := parseAmount(req.MinAmount, "minamount")
+	if err != nil {
+		return nil, err
+	}
+	maxAmount, hasMax, err := parseAmount(req.MaxAmount, "maxamount")
+	if err != nil {
+		return nil, err
 	}
 
-	for _, address := range addressStrings {
+	// Addresses are listed in the order of their program hashes, which is
+	// the order of the unspent index.
+	type account struct {
+		programHash Uint168
+		address     string
+	}
+	var accounts []account
+	visited := make(map[Uint168]struct{})
+	for _, address := range req.Addresses {
 		programHash, err := Uint168FromAddress(address)
 		if err != nil {
 			return nil, errors.New("Invalid address: " + address)
 		}
-		differentAssets, err := s.store.GetUnspents(*programHash)
-		if err != nil {
-			return nil, errors.New("cannot get asset with program")
+		if _, ok := visited[*programHash]; ok {
+			continue
 		}
-		for _, asset := range differentAssets {
-			for _, unspent := range asset {
-				tx, height, err := s.store.GetTransaction(unspent.TxID)
-				if err != nil {
-					return nil, errors.New("unknown transaction " + unspent.TxID.String() + " from persisted utxo")
+		visited[*programHash] = struct{}{}
+		accounts = append(accounts, account{*programHash, address})
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return bytes.Compare(accounts[i].programHash.Bytes(), accounts[j].programHash.Bytes()) < 0
+	})
+
+	indexed := order.indexed()
+	var entries []*unspentEntry
+	var next string
+	for _, account := range accounts {
+		// Pages in the index order seek to the cursor rather than loading
+		// the outputs before it.
+		var start []byte
+		if indexed && after != nil {
+			c := bytes.Compare(account.programHash.Bytes(), after.key[:21])
+			if c < 0 {
+				continue
+			}
+			if c == 0 {
+				start = after.key
+			}
+		}
+
+		var iterErr error
+		err = s.store.ListUnspents(account.programHash, assetID, start, func(key []byte, height uint32, unspent *blockchain.UTXO) bool {
+			if minConf > 0 && (height > bestHeight || bestHeight-height+1 < minConf) {
+				return true
+			}
+			value, err := scaledValue(unspent.AssetID, unspent.Value)
+			if err != nil {
+				iterErr = err
+				return false
+			}
+			if (hasMin && value.Cmp(minAmount) < 0) || (hasMax && value.Cmp(maxAmount) > 0) {
+				return true
+			}
+			entry := &unspentEntry{
+				key:     key,
+				address: account.address,
+				assetID: unspent.AssetID,
+				txID:    unspent.TxID,
+				txIDStr: service.ToReversedString(unspent.TxID),
+				index:   unspent.Index,
+				height:  height,
+				value:   value,
+				unspent: unspent,
+			}
+			if indexed {
+				if paged && len(entries) == count {
+					next = order.cursor(entries[count-1])
+					return false
 				}
-				allResults = append(allResults, UTXOInfo{
-					Amount:        unspent.ValueString(),
-					AssetId:       BytesToHexString(BytesReverse(unspent.AssetID[:])),
-					Txid:          BytesToHexString(BytesReverse(unspent.TxID[:])),
-					VOut:          unspent.Index,
-					Address:       address,
-					Confirmations: bestHeight - height + 1,
-					OutputLock:    tx.Outputs[unspent.Index].OutputLock,
-				})
+			} else if after != nil && order.cmp(after, entry) >= 0 {
+				return true
 			}
+			entries = append(entries, entry)
+			return true
+		})
+		if err == nil {
+			err = iterErr
+		}
+		if err != nil {
+			return nil, errors.New("cannot get asset with program")
+		}
+		if len(next) > 0 {
+			break
 		}
 	}
-	if assetID, ok := param.String("assetid"); ok {
-		for _, result := range allResults {
-			if result.AssetId == assetID {
-				results = append(results, result)
+
+	if !indexed {
+		sort.Slice(entries, func(i, j int) bool {
+			return order.cmp(entries[i], entries[j]) < 0
+		})
+		if paged && len(entries) > count {
+			entries = entries[:count]
+			next = order.cursor(entries[count-1])
+		}
+	}
+
+	results := make([]UTXOInfo, 0, len(entries))
+	for _, entry := range entries {
+		tx, _, err := s.store.GetTransaction(entry.txID)
+		if err != nil || int(entry.index) >= len(tx.Outputs) {
+			return nil, errors.New("unknown transaction " + entry.txID.String() + " from persisted utxo")
+		}
+		amount := entry.unspent.UnitsString()
+		if !raw {
+			precision, ok := s.assetPrecision(entry.assetID)
+			if !ok {
+				precision = core.TokenPrecision
 			}
+			amount = entry.unspent.ValueString(precision)
 		}
-	} else {
-		results = allResults
+		results = append(results, UTXOInfo{
+			Amount:        amount,
+			AssetId:       service.ToReversedString(entry.assetID),
+			Txid:          entry.txIDStr,
+			VOut:          entry.index,
+			Address:       entry.address,
+			Confirmations: bestHeight - entry.height + 1,
+			OutputLock:    tx.Outputs[entry.index].OutputLock,
+		})
+	}
+
+	if !paged {
+		return results, nil
 	}
-	return results, nil
+	return UTXOPage{UTXOs: results, NextCursor: next}, nil
 }
 
 func (s *HttpService) GetAssetByHash(param http.Params) (interface{}, error) {
@@ -322,16 +603,416 @@ func (s *HttpService) GetAssetByHash(param http.Params) (interface{}, error) {
 }
 
 func (s *HttpService) GetAssetList(param http.Params) (interface{}, error) {
-	var assetArray []AssetInfo
-	assets := s.store.GetAssets()
-	for assetID, asset := range assets {
+	cursor, count, paged, err := pageParams(param)
+	if err != nil {
+		return nil, err
+	}
+	if len(cursor) > 0 && len(cursor) != blockchain.AssetCursorSize {
+		return nil, errors.New("invalid cursor")
+	}
+	var req AssetListRequest
+	if !decodeParams(param, &req) {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	var filter *Uint256
+	if len(req.AssetID) > 0 {
+		id, err := ParseHash(req.AssetID)
+		if err != nil {
+			return nil, errors.New("invalid assetid " + req.AssetID)
+		}
+		filter = &id
+	}
+	minConf := req.MinConf
+	bestHeight := s.store.GetHeight()
+
+	assetArray := make([]AssetInfo, 0)
+	var last []byte
+	var next string
+	err = s.store.ListAssets(cursor, func(cursor []byte, assetID Uint256, asset *blockchain.AssetInfo) bool {
+		if filter != nil && !filter.IsEqual(assetID) {
+			return true
+		}
+		if minConf > 0 && (asset.Height > bestHeight || bestHeight-asset.Height+1 < minConf) {
+			return true
+		}
+		if paged && len(assetArray) == count {
+			next = BytesToHexString(last)
+			return false
+		}
 		assetArray = append(assetArray, AssetInfo{
 			asset.Name,
 			asset.Description,
 			asset.Precision,
 			asset.Height,
 			BytesToHexString(BytesReverse(assetID[:]))})
+		last = cursor
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !paged {
+		return assetArray, nil
+	}
+	return AssetPage{Assets: assetArray, NextCursor: next}, nil
+}
+
+func (s *HttpService) GetAssetRegistrationFee(param http.Params) (interface{}, error) {
+	name, ok := param.String("name")
+	if !ok || len(name) == 0 {
+		return nil, errors.New(service.InvalidParams.String())
 	}
 
-	return assetArray, nil
+	height := s.store.GetHeight() + 1
+	registered := false
+	for _, asset := range s.store.GetAssets() {
+		if s.cfg.AssetParams.IsNameConflict(name, asset.Name, height) {
+			registered = true
+			break
+		}
+	}
+	reserved := s.cfg.AssetParams.IsReserved(name, height)
+
+	return AssetRegistrationFee{
+		Name:      name,
+		Fee:       s.cfg.AssetParams.RegisterFee(name, height).String(),
+		Reserved:  reserved,
+		Available: !reserved && !registered,
+	}, nil
+}
+
+func (s *HttpService) GetMempoolInfo(param http.Params) (interface{}, error) {
+	info := s.cfg.TxPool.Info()
+	result := MempoolInfo{
+		Size:        info.Count,
+		Bytes:       info.Size,
+		MaxSize:     info.MaxCount,
+		MaxBytes:    info.MaxSize,
+		MinRelayFee: info.MinFeeRate.String(),
+		Addresses:   make(map[string]MempoolUsage, len(info.Addresses)),
+		Assets:      make(map[string]MempoolUsage, len(info.Assets)),
+	}
+	for programHash, usage := range info.Addresses {
+		address, err := programHash.ToAddress()
+		if err != nil {
+			continue
+		}
+		result.Addresses[address] = MempoolUsage{Size: usage.Count, Bytes: usage.Size}
+	}
+	for assetID, usage := range info.Assets {
+		result.Assets[service.ToReversedString(assetID)] = MempoolUsage{Size: usage.Count, Bytes: usage.Size}
+	}
+	return result, nil
+}
+
+func (s *HttpService) TestMempoolAccept(param http.Params) (interface{}, error) {
+	str, ok := param.String("data")
+	if !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	data, err := HexStringToBytes(str)
+	if err != nil {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	var tx types.Transaction
+	if err := tx.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, errors.New("invalid transaction: " + err.Error())
+	}
+
+	result := MempoolAcceptResult{TxID: service.ToReversedString(tx.Hash())}
+	if s.cfg.TxPool.GetTransaction(tx.Hash()) != nil {
+		result.Reason = "transaction already in pool"
+		return result, nil
+	}
+	if _, _, err := s.store.GetTransaction(tx.Hash()); err == nil {
+		result.Reason = "transaction already in blockchain"
+		return result, nil
+	}
+
+	if err := s.cfg.Validator.CheckTransactionSanity(&tx); err != nil {
+		result.Code, result.Reason = ruleErrorInfo(err)
+		return result, nil
+	}
+	if err := s.cfg.Validator.CheckTransactionContext(&tx); err != nil {
+		result.Code, result.Reason = ruleErrorInfo(err)
+		return result, nil
+	}
+	if spender, ok := s.cfg.TxPool.Conflict(&tx); ok {
+		result.Code = int(mp.ErrPoolConflict)
+		result.Reason = "transaction double spends transaction in pool"
+		result.Conflict = service.ToReversedString(spender)
+		return result, nil
+	}
+
+	fees, err := s.transactionFees(&tx)
+	if err != nil {
+		result.Reason = err.Error()
+		return result, nil
+	}
+	result.Allowed = true
+	result.Fees = fees
+	return result, nil
+}
+
+// ruleErrorInfo returns the error code and description of a rule error, or
+// zero code with the error message for other errors.
+func ruleErrorInfo(err error) (int, string) {
+	if ruleErr, ok := err.(mempool.RuleError); ok {
+		return int(ruleErr.ErrorCode), ruleErr.Description
+	}
+	return 0, err.Error()
+}
+
+// transactionFees returns the fee of each asset in the transaction, which is
+// the value of referenced outputs minus the value of outputs.
+func (s *HttpService) transactionFees(tx *types.Transaction) (map[string]string, error) {
+	references, err := s.store.GetTxReference(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	elaAssetID := types.GetSystemAssetId()
+	var elaFee Fixed64
+	tokenFees := make(map[Uint256]*core.TokenAmount)
+	for _, output := range references {
+		if output.AssetID.IsEqual(elaAssetID) {
+			elaFee += output.Value
+		} else {
+			if _, ok := tokenFees[output.AssetID]; !ok {
+				tokenFees[output.AssetID] = new(core.TokenAmount)
+			}
+			tokenFees[output.AssetID].Add(core.NewTokenAmount(&output.TokenValue))
+		}
+	}
+	for _, output := range tx.Outputs {
+		if output.AssetID.IsEqual(elaAssetID) {
+			elaFee -= output.Value
+		} else {
+			if _, ok := tokenFees[output.AssetID]; !ok {
+				tokenFees[output.AssetID] = new(core.TokenAmount)
+			}
+			tokenFees[output.AssetID].Sub(core.NewTokenAmount(&output.TokenValue))
+		}
+	}
+
+	fees := map[string]string{service.ToReversedString(elaAssetID): elaFee.String()}
+	for assetID, fee := range tokenFees {
+		precision := byte(core.TokenPrecision)
+		if asset, err := s.store.GetAsset(assetID); err == nil {
+			precision = asset.Precision
+		}
+		fees[service.ToReversedString(assetID)] = fee.Format(precision)
+	}
+	return fees, nil
+}
+
+func (s *HttpService) ValidateRawTransaction(param http.Params) (interface{}, error) {
+	str, ok := param.String("data")
+	if !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	data, err := HexStringToBytes(str)
+	if err != nil {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	var tx types.Transaction
+	if err := tx.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, errors.New("invalid transaction: " + err.Error())
+	}
+
+	violations := s.cfg.Diagnoser.Diagnose(&tx)
+	result := ValidationResult{
+		TxID:       service.ToReversedString(tx.Hash()),
+		Valid:      len(violations) == 0,
+		Violations: make([]RuleViolation, 0, len(violations)),
+	}
+	for _, v := range violations {
+		result.Violations = append(result.Violations, RuleViolation{
+			Rule:        v.Rule,
+			Code:        int(v.Code),
+			Description: v.Description,
+		})
+	}
+	return result, nil
+}
+
+// hashParam returns the hash parameter in reversed hex string.
+func hashParam(param http.Params, key string) (Uint256, bool) {
+	str, ok := param.String(key)
+	if !ok {
+		return Uint256{}, false
+	}
+	hash, err := ParseHash(str)
+	if err != nil {
+		return Uint256{}, false
+	}
+	return hash, true
+}
+
+func (s *HttpService) GetBlockFilter(param http.Params) (interface{}, error) {
+	hash, ok := hashParam(param, "blockhash")
+	if !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	filter, err := s.store.GetBlockFilter(hash)
+	if err != nil {
+		return nil, errors.New("block filter not found")
+	}
+	header, err := s.store.GetFilterHeader(hash)
+	if err != nil {
+		return nil, errors.New("filter header not found")
+	}
+	return BlockFilter{
+		Filter: BytesToHexString(filter),
+		Header: service.ToReversedString(header),
+	}, nil
+}
+
+func (s *HttpService) GetFilterHeaders(param http.Params) (interface{}, error) {
+	startHeight, ok := param.Uint("startheight")
+	if !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	stopHash, ok := hashParam(param, "stophash")
+	if !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	hashes, err := s.cfg.CFilters.BlockRange(startHeight, stopHash, msg.MaxCFHeadersPerMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := FilterHeaders{FilterHeaders: make([]string, 0, len(hashes))}
+	var prevHeader Uint256
+	if startHeight > 0 {
+		prevHash, err := s.store.GetBlockHash(startHeight - 1)
+		if err != nil {
+			return nil, err
+		}
+		if prevHeader, err = s.store.GetFilterHeader(prevHash); err != nil {
+			return nil, errors.New("filter header not found")
+		}
+	}
+	result.PrevFilterHeader = service.ToReversedString(prevHeader)
+	for _, hash := range hashes {
+		header, err := s.store.GetFilterHeader(hash)
+		if err != nil {
+			return nil, errors.New("filter header not found")
+		}
+		result.FilterHeaders = append(result.FilterHeaders, service.ToReversedString(header))
+	}
+	return result, nil
+}
+
+func (s *HttpService) GetTxOut(param http.Params) (interface{}, error) {
+	str, ok := param.String("txid")
+	if !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	txID, err := ParseHash(str)
+	if err != nil {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	vout, ok := param.Uint("vout")
+	if !ok || vout > math.MaxUint16 {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	includeMempool, ok := param.Bool("includemempool")
+	if !ok {
+		includeMempool = true
+	}
+	raw, _ := param.Bool("rawunits")
+
+	bestHeight := s.store.GetHeight()
+	bestBlock, err := s.store.GetBlockHash(bestHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx *types.Transaction
+	var confirmations uint32
+	if includeMempool {
+		outpoint := types.OutPoint{TxID: txID, Index: uint16(vout)}
+		for _, poolTx := range s.cfg.TxPool.GetTxsInPool() {
+			for _, input := range poolTx.Inputs {
+				if input.Previous == outpoint {
+					return nil, nil
+				}
+			}
+		}
+		tx = s.cfg.TxPool.GetTransaction(txID)
+	}
+	if tx == nil {
+		var height uint32
+		tx, height, err = s.store.GetTransaction(txID)
+		if err != nil || int(vout) >= len(tx.Outputs) {
+			return nil, nil
+		}
+		output := tx.Outputs[vout]
+		if !s.store.IsUnspent(output.ProgramHash, output.AssetID, height, txID, vout) {
+			return nil, nil
+		}
+		confirmations = bestHeight - height + 1
+	} else if int(vout) >= len(tx.Outputs) {
+		return nil, nil
+	}
+
+	output := tx.Outputs[vout]
+	address, err := output.ProgramHash.ToAddress()
+	if err != nil {
+		return nil, err
+	}
+	info := TxOutInfo{
+		BestBlock:     service.ToReversedString(bestBlock),
+		Confirmations: confirmations,
+		AssetID:       service.ToReversedString(output.AssetID),
+		Value:         outputValue(output, s.assetPrecision),
+		Address:       address,
+		OutputLock:    output.OutputLock,
+		Coinbase:      tx.IsCoinBaseTx(),
+	}
+	if raw {
+		info.Value = outputUnits(output)
+	}
+	return info, nil
+}
+
+func (s *HttpService) GetTxOutSetInfo(param http.Params) (interface{}, error) {
+	raw, _ := param.Bool("rawunits")
+
+	stats, err := s.store.GetUTXOSetStats()
+	if err != nil {
+		return nil, err
+	}
+
+	result := UTXOSetInfo{
+		Height:    stats.Height,
+		BestBlock: service.ToReversedString(stats.BlockHash),
+		TxOuts:    stats.TxOuts,
+		Hash:      service.ToReversedString(stats.Hash),
+		Assets:    make([]AssetUTXOInfo, 0, len(stats.Assets)),
+	}
+	for assetID, asset := range stats.Assets {
+		info := AssetUTXOInfo{
+			AssetID:   service.ToReversedString(assetID),
+			TxOuts:    asset.Count,
+			Addresses: asset.Addresses,
+			Total:     asset.Total.String(),
+		}
+		if !raw {
+			if assetID.IsEqual(types.GetSystemAssetId()) {
+				info.Total = Fixed64(asset.Total.Int64()).String()
+			} else if precision, ok := s.assetPrecision(assetID); ok {
+				info.Total = core.NewTokenAmount(asset.Total).Format(precision)
+			} else {
+				info.Total = core.NewTokenAmount(asset.Total).String()
+			}
+		}
+		result.Assets = append(result.Assets, info)
+	}
+	sort.Slice(result.Assets, func(i, j int) bool {
+		return result.Assets[i].AssetID < result.Assets[j].AssetID
+	})
+	return result, nil
 }
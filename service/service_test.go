@@ -0,0 +1,216 @@
+package service
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/coinselect"
+	mp "github.com/elastos/Elastos.ELA.SideChain.Token/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/wallet"
+
+	"github.com/elastos/Elastos.ELA.SideChain/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/utils/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionInfoAmounts(t *testing.T) {
+	tokenA, tokenB, unknown := common.Uint256{0x01}, common.Uint256{0x02}, common.Uint256{0x03}
+	precision := func(assetID common.Uint256) (byte, bool) {
+		switch assetID {
+		case tokenA:
+			return 8, true
+		case tokenB:
+			return 0, true
+		}
+		return 0, false
+	}
+
+	tokenOutput := func(assetID common.Uint256, value *big.Int) *types.Output {
+		return &types.Output{AssetID: assetID, TokenValue: *value}
+	}
+	half := new(big.Int).Mul(big.NewInt(5), new(big.Int).Exp(big.NewInt(10), big.NewInt(17), nil))
+	large, _ := new(big.Int).SetString("123456789012345678901234567890123456789012345678", 10)
+	whole := new(big.Int).Exp(big.NewInt(10), big.NewInt(40), nil)
+
+	tx := &types.Transaction{
+		TxType:  types.TransferAsset,
+		Payload: new(types.PayloadTransferAsset),
+		Outputs: []*types.Output{
+			{AssetID: types.GetSystemAssetId(), Value: common.Fixed64(150000000)},
+			tokenOutput(tokenA, half),
+			tokenOutput(tokenA, big.NewInt(1)),
+			tokenOutput(tokenB, half),
+			tokenOutput(tokenB, large),
+			tokenOutput(tokenB, whole),
+			tokenOutput(unknown, half),
+		},
+	}
+
+	cfg := &service.Config{GetPayloadInfo: GetPayloadInfo}
+	cfg.GetTransactionInfo = TransactionInfoFunc(precision)
+	info := GetTokenTransactionInfo(cfg, nil, tx, true)
+	values := make([]string, 0, len(info.Outputs))
+	for _, output := range info.Outputs {
+		values = append(values, output.Value)
+	}
+	assert.Equal(t, []string{
+		common.Fixed64(150000000).String(),
+		"0.50000000",
+		"0.000000000000000001",
+		"0.5",
+		"123456789012345678901234567890.123456789012345678",
+		"10000000000000000000000",
+		"0.500000000000000000",
+	}, values)
+
+	rawUnits(info, tx)
+	values = values[:0]
+	for _, output := range info.Outputs {
+		values = append(values, output.Value)
+	}
+	assert.Equal(t, []string{
+		"150000000",
+		half.String(),
+		"1",
+		half.String(),
+		large.String(),
+		whole.String(),
+		half.String(),
+	}, values)
+
+	// Without the precisions, token values have all the decimal places.
+	cfg.GetTransactionInfo = GetTransactionInfo
+	info = GetTokenTransactionInfo(cfg, nil, tx, true)
+	assert.Equal(t, "0.500000000000000000", info.Outputs[3].Value)
+	assert.Equal(t, "10000000000000000000000.000000000000000000", info.Outputs[5].Value)
+}
+
+func TestGetPayloadInfo(t *testing.T) {
+	assert.Equal(t, &TransferAssetInfo{}, GetPayloadInfo(new(types.PayloadTransferAsset), 0))
+
+	info := GetPayloadInfo(&types.PayloadRecord{RecordType: "memo", RecordData: []byte("hello")}, 0)
+	assert.Equal(t, &RecordInfo{RecordType: "memo", RecordData: "68656c6c6f", Text: "hello"}, info)
+	info = GetPayloadInfo(&types.PayloadRecord{RecordType: "bin", RecordData: []byte{0xff, 0xfe}}, 0)
+	assert.Equal(t, &RecordInfo{RecordType: "bin", RecordData: "fffe"}, info)
+
+	info = GetPayloadInfo(new(types.PayloadRechargeToSideChain), 0xff)
+	unknown, ok := info.(*UnknownPayloadInfo)
+	assert.True(t, ok)
+	assert.Equal(t, byte(0xff), unknown.Version)
+	assert.NotEmpty(t, unknown.Error)
+
+	_, ok = GetPayloadInfo(new(types.PayloadRechargeToSideChain), types.RechargeToSideChainPayloadVersion1).(*service.RechargeToSideChainInfoV1)
+	assert.True(t, ok)
+	assert.Nil(t, GetPayloadInfo(nil, 0))
+}
+
+func TestGetTxOut(t *testing.T) {
+	dir, err := ioutil.TempDir("", "service")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	chainParams := &params.MainNetParams
+	store, err := blockchain.NewChainStore(params.GenesisBlock, chainParams.ElaAssetId,
+		&params.MainNetAssetParams, filepath.Join(dir, "chain"), true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+
+	mempoolCfg := mp.Config{ChainParams: chainParams, ChainStore: store.ChainStore}
+	mempoolCfg.FeeHelper = mp.NewFeeHelper(&mempoolCfg)
+	txPool := mp.NewTxPool(&mempoolCfg, mempool.New(&mempool.Config{
+		ChainParams: chainParams,
+		ChainStore:  store.ChainStore,
+		Validator:   mp.NewValidator(&mempoolCfg),
+		FeeHelper:   mempoolCfg.FeeHelper.FeeHelper,
+	}))
+	w, err := wallet.Open(&wallet.Config{
+		Path:        filepath.Join(dir, "wallet.json"),
+		Store:       store,
+		TxPool:      txPool,
+		ChainParams: chainParams,
+		Selector:    coinselect.New(&coinselect.Config{Store: store, ChainParams: chainParams}),
+	})
+	assert.NoError(t, err)
+	address, err := w.NewAddress("password")
+	assert.NoError(t, err)
+	programHash, err := common.Uint168FromAddress(address)
+	assert.NoError(t, err)
+
+	funding := &types.Transaction{
+		TxType:     types.TransferAsset,
+		Payload:    &types.PayloadTransferAsset{},
+		Attributes: []*types.Attribute{},
+		Inputs:     []*types.Input{},
+		Outputs: []*types.Output{{
+			AssetID:     chainParams.ElaAssetId,
+			Value:       100000000,
+			ProgramHash: *programHash,
+		}},
+		Programs: []*types.Program{},
+	}
+	header := params.GenesisBlock.Header
+	header.Height = 1
+	header.Previous = params.GenesisBlock.Hash()
+	assert.NoError(t, store.SaveBlock(&types.Block{Header: header, Transactions: []*types.Transaction{funding}}))
+
+	s := &HttpService{cfg: &Config{Store: store, TxPool: txPool}, store: store}
+	txOut := func(txID common.Uint256, includeMempool bool) interface{} {
+		result, err := s.GetTxOut(http.Params{
+			"txid":           service.ToReversedString(txID),
+			"vout":           float64(0),
+			"includemempool": includeMempool,
+		})
+		assert.NoError(t, err)
+		return result
+	}
+
+	// An unspent output in the chain.
+	info, ok := txOut(funding.Hash(), true).(TxOutInfo)
+	if assert.True(t, ok) {
+		assert.Equal(t, uint32(1), info.Confirmations)
+		assert.Equal(t, address, info.Address)
+		assert.Equal(t, "1", info.Value)
+	}
+
+	// The output spent by a transaction in pool is spent only if the pool
+	// is included, and the output of the pool transaction is unconfirmed.
+	tx, err := w.CreateTransaction([]*types.Output{{
+		AssetID:     chainParams.ElaAssetId,
+		Value:       10000000,
+		ProgramHash: common.Uint168{0x21, 0x01},
+	}}, nil, nil, 0, nil, "password")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, txPool.AppendToTxPool(tx))
+	assert.Nil(t, txOut(funding.Hash(), true))
+	assert.NotNil(t, txOut(funding.Hash(), false))
+	info, ok = txOut(tx.Hash(), true).(TxOutInfo)
+	if assert.True(t, ok) {
+		assert.Equal(t, uint32(0), info.Confirmations)
+	}
+	assert.Nil(t, txOut(tx.Hash(), false))
+
+	// The output is spent once the transaction is in a block.
+	header.Height = 2
+	header.Previous = store.GetCurrentBlockHash()
+	assert.NoError(t, store.SaveBlock(&types.Block{Header: header, Transactions: []*types.Transaction{tx}}))
+	assert.Nil(t, txOut(funding.Hash(), false))
+	info, ok = txOut(tx.Hash(), false).(TxOutInfo)
+	if assert.True(t, ok) {
+		assert.Equal(t, uint32(1), info.Confirmations)
+	}
+
+	// Unknown outputs are reported as spent.
+	assert.Nil(t, txOut(common.Uint256{0x01}, true))
+}
@@ -1,5 +1,11 @@
 package service
 
+import (
+	"encoding/json"
+
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+)
+
 type AssetInfo struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
@@ -8,6 +14,216 @@ type AssetInfo struct {
 	ID          string `json:"assetid"`
 }
 
+// AssetPage is a page of registered assets, NextCursor is empty on the last
+// page.
+type AssetPage struct {
+	Assets     []AssetInfo `json:"assets"`
+	NextCursor string      `json:"nextcursor,omitempty"`
+}
+
+type UTXOInfo struct {
+	AssetId       string `json:"assetid"`
+	Txid          string `json:"txid"`
+	VOut          uint32 `json:"vout"`
+	Address       string `json:"address"`
+	Amount        string `json:"amount"`
+	Confirmations uint32 `json:"confirmations"`
+	OutputLock    uint32 `json:"outputlock"`
+}
+
+// UTXOPage is a page of unspent outputs, NextCursor is empty on the last page.
+type UTXOPage struct {
+	UTXOs      []UTXOInfo `json:"utxos"`
+	NextCursor string     `json:"nextcursor,omitempty"`
+}
+
+// TransferAssetInfo is the payload info of transfer transactions, which
+// carry no data in the payload.
+type TransferAssetInfo struct{}
+
+// RecordInfo is the payload info of record transactions, Text is the record
+// data if it is valid UTF-8.
+type RecordInfo struct {
+	RecordType string `json:"recordtype"`
+	RecordData string `json:"recorddata"`
+	Text       string `json:"text,omitempty"`
+}
+
+// UnknownPayloadInfo is the payload info of a payload type or version the
+// node can not decode.
+type UnknownPayloadInfo struct {
+	Version byte   `json:"version"`
+	Error   string `json:"error"`
+}
+
+type TxOutInfo struct {
+	BestBlock     string `json:"bestblock"`
+	Confirmations uint32 `json:"confirmations"`
+	AssetID       string `json:"assetid"`
+	Value         string `json:"value"`
+	Address       string `json:"address"`
+	OutputLock    uint32 `json:"outputlock"`
+	Coinbase      bool   `json:"coinbase"`
+}
+
+type AssetUTXOInfo struct {
+	AssetID   string `json:"assetid"`
+	TxOuts    int    `json:"txouts"`
+	Addresses int    `json:"addresses"`
+	Total     string `json:"total"`
+}
+
+type UTXOSetInfo struct {
+	Height    uint32          `json:"height"`
+	BestBlock string          `json:"bestblock"`
+	TxOuts    int             `json:"txouts"`
+	Hash      string          `json:"hash"`
+	Assets    []AssetUTXOInfo `json:"assets"`
+}
+
+type AssetRegistrationFee struct {
+	Name      string `json:"name"`
+	Fee       string `json:"fee"`
+	Reserved  bool   `json:"reserved"`
+	Available bool   `json:"available"`
+}
+
+type MempoolInfo struct {
+	Size        int    `json:"size"`
+	Bytes       int    `json:"bytes"`
+	MaxSize     int    `json:"maxsize"`
+	MaxBytes    int    `json:"maxbytes"`
+	MinRelayFee string `json:"minrelayfee"`
+
+	Addresses map[string]MempoolUsage `json:"addresses"`
+	Assets    map[string]MempoolUsage `json:"assets"`
+}
+
+type MempoolUsage struct {
+	Size  int `json:"size"`
+	Bytes int `json:"bytes"`
+}
+
+type MempoolAcceptResult struct {
+	TxID     string            `json:"txid"`
+	Allowed  bool              `json:"allowed"`
+	Code     int               `json:"code,omitempty"`
+	Reason   string            `json:"reason,omitempty"`
+	Conflict string            `json:"conflict,omitempty"`
+	Fees     map[string]string `json:"fees,omitempty"`
+}
+
+type RuleViolation struct {
+	Rule        string `json:"rule"`
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+}
+
+type ValidationResult struct {
+	TxID       string          `json:"txid"`
+	Valid      bool            `json:"valid"`
+	Violations []RuleViolation `json:"violations"`
+}
+
+// OutputInfo extends the side chain output info with the output memo.
+type OutputInfo struct {
+	service.OutputInfo
+	Memo string `json:"memo,omitempty"`
+}
+
+// TransactionInfo extends the side chain transaction info with outputs carry
+// their memos.
+type TransactionInfo struct {
+	*service.TransactionInfo
+	Outputs []OutputInfo `json:"vout"`
+}
+
+type RawTxInput struct {
+	TxID     string `json:"txid"`
+	VOut     uint16 `json:"vout"`
+	Sequence uint32 `json:"sequence"`
+}
+
+type RawTxOutput struct {
+	Address    string `json:"address"`
+	AssetID    string `json:"assetid"`
+	Amount     string `json:"amount"`
+	Memo       string `json:"memo,omitempty"`
+	OutputLock uint32 `json:"outputlock,omitempty"`
+}
+
+// Amount is a decimal amount of whole tokens, it is decoded from a JSON
+// string or number.
+type Amount string
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*a = Amount(str)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	*a = Amount(number)
+	return nil
+}
+
+// AssetListRequest is the parameters of getassetlist besides the page.
+type AssetListRequest struct {
+	AssetID string `json:"assetid,omitempty"`
+	MinConf uint32 `json:"minconf,omitempty"`
+}
+
+// ListUnspentRequest is the parameters of listunspent besides the page.
+type ListUnspentRequest struct {
+	Addresses []string `json:"addresses"`
+	AssetID   string   `json:"assetid,omitempty"`
+	RawUnits  bool     `json:"rawunits,omitempty"`
+	MinConf   uint32   `json:"minconf,omitempty"`
+	MinAmount Amount   `json:"minamount,omitempty"`
+	MaxAmount Amount   `json:"maxamount,omitempty"`
+	Sort      string   `json:"sort,omitempty"`
+	Desc      bool     `json:"desc,omitempty"`
+}
+
+// CreateRawTransactionRequest is the parameters of createrawtransaction, the
+// inputs are selected from FromAddresses if Inputs is empty.
+type CreateRawTransactionRequest struct {
+	Inputs        []RawTxInput  `json:"inputs,omitempty"`
+	Outputs       []RawTxOutput `json:"outputs"`
+	LockTime      uint32        `json:"locktime,omitempty"`
+	FromAddresses []string      `json:"fromaddresses,omitempty"`
+	ChangeAddress string        `json:"changeaddress,omitempty"`
+	Fee           string        `json:"fee,omitempty"`
+	Strategy      string        `json:"strategy,omitempty"`
+}
+
+// SendTokenRequest is the parameters of sendtoken.
+type SendTokenRequest struct {
+	Outputs       []RawTxOutput `json:"outputs"`
+	Password      string        `json:"password"`
+	ChangeAddress string        `json:"changeaddress,omitempty"`
+	Fee           string        `json:"fee,omitempty"`
+	Strategy      string        `json:"strategy,omitempty"`
+}
+
+type OutputPoint struct {
+	TxID string `json:"txid"`
+	VOut uint16 `json:"vout"`
+}
+
+type BlockFilter struct {
+	Filter string `json:"filter"`
+	Header string `json:"header"`
+}
+
+type FilterHeaders struct {
+	PrevFilterHeader string   `json:"prevfilterheader"`
+	FilterHeaders    []string `json:"filterheaders"`
+}
+
 type ServerInfo struct {
 	Compile   string      `json:"compile"`   // The compile version of this server node
 	Height    uint32      `json:"height"`    // The ServerNode latest block height
@@ -33,3 +249,18 @@ type PeerInfo struct {
 	LastPingTime   string `json:"lastpingtime"`
 	LastPingMicros int64  `json:"lastpingmicros"`
 }
+
+type WalletBalance struct {
+	AssetID   string `json:"assetid"`
+	Spendable string `json:"spendable"`
+	Locked    string `json:"locked"`
+	Pending   string `json:"pending"`
+}
+
+type WalletTransaction struct {
+	TxID          string            `json:"txid"`
+	Confirmations uint32            `json:"confirmations"`
+	Pending       bool              `json:"pending"`
+	Amounts       map[string]string `json:"amounts"`
+	Fee           string            `json:"fee,omitempty"`
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/coinselect"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+
+	. "github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/utils/http"
+)
+
+const defaultWalletTxCount = 10
+
+// errWalletDisabled is returned by wallet RPCs if the wallet is not enabled.
+var errWalletDisabled = errors.New("wallet is not enabled")
+
+// walletAmount formats the amount of the asset in base units, ELA amounts are
+// counted in the smallest unit of Fixed64.
+func (s *HttpService) walletAmount(assetID Uint256, value *big.Int, raw bool) string {
+	if raw {
+		return value.String()
+	}
+	if assetID.IsEqual(types.GetSystemAssetId()) {
+		return Fixed64(value.Int64()).String()
+	}
+	if precision, ok := s.assetPrecision(assetID); ok {
+		return core.NewTokenAmount(value).Format(precision)
+	}
+	return core.NewTokenAmount(value).String()
+}
+
+func (s *HttpService) GetNewAddress(param http.Params) (interface{}, error) {
+	if s.cfg.Wallet == nil {
+		return nil, errWalletDisabled
+	}
+	password, ok := param.String("password")
+	if !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	return s.cfg.Wallet.NewAddress(password)
+}
+
+func (s *HttpService) GetWalletBalance(param http.Params) (interface{}, error) {
+	if s.cfg.Wallet == nil {
+		return nil, errWalletDisabled
+	}
+	minConf, ok := param.Uint("minconf")
+	if !ok {
+		minConf = 1
+	}
+	raw, _ := param.Bool("rawunits")
+
+	balances, err := s.cfg.Wallet.Balances(minConf)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]WalletBalance, 0, len(balances))
+	for assetID, b := range balances {
+		result = append(result, WalletBalance{
+			AssetID:   service.ToReversedString(assetID),
+			Spendable: s.walletAmount(assetID, b.Spendable, raw),
+			Locked:    s.walletAmount(assetID, b.Locked, raw),
+			Pending:   s.walletAmount(assetID, b.Pending, raw),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].AssetID < result[j].AssetID
+	})
+	return result, nil
+}
+
+func (s *HttpService) SendToken(param http.Params) (interface{}, error) {
+	if s.cfg.Wallet == nil {
+		return nil, errWalletDisabled
+	}
+	var req SendTokenRequest
+	if !decodeParams(param, &req) || len(req.Outputs) == 0 {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	if _, ok := param["password"]; !ok {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+
+	txOutputs := make([]*types.Output, 0, len(req.Outputs))
+	memos := make(map[int][]byte)
+	for i, o := range req.Outputs {
+		output, memo, err := createOutput(o, s.assetPrecision)
+		if err != nil {
+			return nil, err
+		}
+		txOutputs = append(txOutputs, output)
+		if len(memo) > 0 {
+			memos[i] = memo
+		}
+	}
+
+	var change *Uint168
+	if len(req.ChangeAddress) > 0 {
+		programHash, err := Uint168FromAddress(req.ChangeAddress)
+		if err != nil {
+			return nil, errors.New("invalid change address " + req.ChangeAddress)
+		}
+		change = programHash
+	}
+
+	var fee Fixed64
+	if len(req.Fee) > 0 {
+		value, err := StringToFixed64(req.Fee)
+		if err != nil {
+			return nil, errors.New("invalid fee " + req.Fee)
+		}
+		fee = *value
+	}
+
+	var strategy coinselect.Strategy
+	if len(req.Strategy) > 0 {
+		var err error
+		strategy, err = coinselect.ParseStrategy(req.Strategy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.cfg.Wallet.Send(txOutputs, memos, change, fee, strategy, req.Password, func(tx *types.Transaction) error {
+		buf := new(bytes.Buffer)
+		if err := tx.Serialize(buf); err != nil {
+			return err
+		}
+		_, err := s.SendRawTransaction(http.Params{"data": BytesToHexString(buf.Bytes())})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return service.ToReversedString(tx.Hash()), nil
+}
+
+func (s *HttpService) ListWalletTransactions(param http.Params) (interface{}, error) {
+	if s.cfg.Wallet == nil {
+		return nil, errWalletDisabled
+	}
+	count, ok := param.Uint("count")
+	if !ok {
+		count = defaultWalletTxCount
+	}
+	if count == 0 || count > maxPageLimit {
+		return nil, errors.New(service.InvalidParams.String())
+	}
+	skip, _ := param.Uint("skip")
+	raw, _ := param.Bool("rawunits")
+
+	records, err := s.cfg.Wallet.Transactions(int(skip), int(count))
+	if err != nil {
+		return nil, err
+	}
+	bestHeight := s.store.GetHeight()
+	result := make([]WalletTransaction, 0, len(records))
+	for _, rec := range records {
+		tx := WalletTransaction{
+			TxID:    service.ToReversedString(rec.TxID),
+			Pending: rec.Pending,
+			Amounts: make(map[string]string, len(rec.Amounts)),
+		}
+		if !rec.Pending && bestHeight >= rec.Height {
+			tx.Confirmations = bestHeight - rec.Height + 1
+		}
+		for assetID, amount := range rec.Amounts {
+			tx.Amounts[service.ToReversedString(assetID)] = s.walletAmount(assetID, amount, raw)
+		}
+		if rec.Fee != nil {
+			tx.Fee = s.walletAmount(types.GetSystemAssetId(), rec.Fee, raw)
+		}
+		result = append(result, tx)
+	}
+	return result, nil
+}
@@ -0,0 +1,612 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	. "github.com/elastos/Elastos.ELA/common"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsEventQueueSize is the number of events waiting to be pushed, events
+	// are dropped when the queue is full, so the block and transaction
+	// processing is never blocked by the WebSocket service.
+	wsEventQueueSize = 1024
+
+	// wsClientQueueSize is the number of messages waiting to be written to a
+	// client, a client falling further behind is disconnected.
+	wsClientQueueSize = 256
+
+	// maxWSSubscriptions is the maximum number of addresses and assets a
+	// client can subscribe.
+	maxWSSubscriptions = 1000
+
+	wsWriteTimeout  = 10 * time.Second
+	wsPongTimeout   = 60 * time.Second
+	wsPingInterval  = wsPongTimeout * 9 / 10
+	wsMaxMessageLen = 64 * 1024
+)
+
+// WebSocket topics a client can subscribe.
+const (
+	TopicBlocks  = "blocks"
+	TopicReorgs  = "reorgs"
+	TopicMempool = "mempool"
+)
+
+// Status of the transaction in a transfer event.
+const (
+	TransferPending    = "pending"
+	TransferConfirmed  = "confirmed"
+	TransferRolledBack = "rolledback"
+)
+
+type wsEventKind int
+
+const (
+	wsBlockPersisted wsEventKind = iota
+	wsBlockRolledBack
+	wsTxAdded
+)
+
+type wsEvent struct {
+	kind  wsEventKind
+	block *types.Block
+	tx    *types.Transaction
+}
+
+// WSRequest is the request from a WebSocket client, Method is "subscribe" or
+// "unsubscribe".
+type WSRequest struct {
+	ID     interface{}       `json:"id"`
+	Method string            `json:"method"`
+	Params WSSubscribeParams `json:"params"`
+}
+
+// WSSubscribeParams defines the topics, addresses and assets to subscribe or
+// unsubscribe.
+type WSSubscribeParams struct {
+	Topics    []string `json:"topics"`
+	Addresses []string `json:"addresses"`
+	Assets    []string `json:"assets"`
+}
+
+// WSResponse is the response of a WebSocket request.
+type WSResponse struct {
+	ID     interface{} `json:"id"`
+	Result interface{} `json:"result"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// WSNotification is the event pushed to the subscribed WebSocket clients.
+type WSNotification struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+// BlockNotification is the data of the "block" and "reorg" events.
+type BlockNotification struct {
+	Hash   string   `json:"hash"`
+	Height uint32   `json:"height"`
+	Time   uint32   `json:"time"`
+	Tx     []string `json:"tx"`
+}
+
+// MempoolNotification is the data of the "mempool" events.
+type MempoolNotification struct {
+	TxID string `json:"txid"`
+	Type byte   `json:"type"`
+	Size int    `json:"size"`
+}
+
+// TransferNotification is the data of the "transfer" events, Addresses and
+// Assets are the subscribed ones involved in the transaction.
+type TransferNotification struct {
+	TxID      string   `json:"txid"`
+	Status    string   `json:"status"`
+	BlockHash string   `json:"blockhash,omitempty"`
+	Height    uint32   `json:"height,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	Assets    []string `json:"assets,omitempty"`
+}
+
+// wsSubscription is the topics, addresses and assets subscribed by a client.
+type wsSubscription struct {
+	topics    map[string]struct{}
+	addresses map[Uint168]struct{}
+	assets    map[Uint256]struct{}
+}
+
+func (s *wsSubscription) watching() bool {
+	return len(s.addresses) > 0 || len(s.assets) > 0
+}
+
+// involved returns the subscribed addresses and assets in the involvement.
+func (s *wsSubscription) involved(inv *involvement) (addresses []string, assets []string) {
+	for programHash := range s.addresses {
+		if _, ok := inv.programHashes[programHash]; ok {
+			address, _ := programHash.ToAddress()
+			addresses = append(addresses, address)
+		}
+	}
+	for assetID := range s.assets {
+		if _, ok := inv.assetIDs[assetID]; ok {
+			assets = append(assets, service.ToReversedString(assetID))
+		}
+	}
+	return addresses, assets
+}
+
+// involvement is the addresses and assets of the outputs and the referenced
+// outputs of a transaction.
+type involvement struct {
+	programHashes map[Uint168]struct{}
+	assetIDs      map[Uint256]struct{}
+}
+
+type wsClient struct {
+	conn *websocket.Conn
+	send chan []byte
+	quit chan struct{}
+	once sync.Once
+
+	mtx sync.Mutex
+	sub wsSubscription
+}
+
+func (c *wsClient) close() {
+	c.once.Do(func() {
+		close(c.quit)
+		c.conn.Close()
+	})
+}
+
+// queue queues the message to write, the client is disconnected if it falls
+// too far behind.
+func (c *wsClient) queue(msg []byte) {
+	select {
+	case c.send <- msg:
+	case <-c.quit:
+	default:
+		c.close()
+	}
+}
+
+func (c *wsClient) notify(event string, data interface{}) {
+	msg, err := json.Marshal(WSNotification{Event: event, Data: data})
+	if err != nil {
+		return
+	}
+	c.queue(msg)
+}
+
+func (c *wsClient) respond(id interface{}, result interface{}, err error) {
+	resp := WSResponse{ID: id, Result: result}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	msg, e := json.Marshal(resp)
+	if e != nil {
+		return
+	}
+	c.queue(msg)
+}
+
+// update subscribes or unsubscribes the topics, addresses and assets.
+func (c *wsClient) update(params *WSSubscribeParams, subscribe bool) error {
+	topics := make([]string, 0, len(params.Topics))
+	for _, topic := range params.Topics {
+		switch topic {
+		case TopicBlocks, TopicReorgs, TopicMempool:
+			topics = append(topics, topic)
+		default:
+			return fmt.Errorf("unknown topic %s", topic)
+		}
+	}
+	addresses := make([]Uint168, 0, len(params.Addresses))
+	for _, address := range params.Addresses {
+		programHash, err := Uint168FromAddress(address)
+		if err != nil {
+			return fmt.Errorf("invalid address %s", address)
+		}
+		addresses = append(addresses, *programHash)
+	}
+	assets := make([]Uint256, 0, len(params.Assets))
+	for _, asset := range params.Assets {
+		assetID, err := ParseHash(asset)
+		if err != nil {
+			return fmt.Errorf("invalid asset id %s", asset)
+		}
+		assets = append(assets, assetID)
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if !subscribe {
+		for _, topic := range topics {
+			delete(c.sub.topics, topic)
+		}
+		for _, programHash := range addresses {
+			delete(c.sub.addresses, programHash)
+		}
+		for _, assetID := range assets {
+			delete(c.sub.assets, assetID)
+		}
+		return nil
+	}
+
+	if len(c.sub.addresses)+len(addresses) > maxWSSubscriptions ||
+		len(c.sub.assets)+len(assets) > maxWSSubscriptions {
+		return fmt.Errorf("subscribe more than %d addresses or assets", maxWSSubscriptions)
+	}
+	for _, topic := range topics {
+		c.sub.topics[topic] = struct{}{}
+	}
+	for _, programHash := range addresses {
+		c.sub.addresses[programHash] = struct{}{}
+	}
+	for _, assetID := range assets {
+		c.sub.assets[assetID] = struct{}{}
+	}
+	return nil
+}
+
+// subscription returns a copy of the subscription of the client.
+func (c *wsClient) subscription() wsSubscription {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	sub := wsSubscription{
+		topics:    make(map[string]struct{}, len(c.sub.topics)),
+		addresses: make(map[Uint168]struct{}, len(c.sub.addresses)),
+		assets:    make(map[Uint256]struct{}, len(c.sub.assets)),
+	}
+	for topic := range c.sub.topics {
+		sub.topics[topic] = struct{}{}
+	}
+	for programHash := range c.sub.addresses {
+		sub.addresses[programHash] = struct{}{}
+	}
+	for assetID := range c.sub.assets {
+		sub.assets[assetID] = struct{}{}
+	}
+	return sub
+}
+
+// WSServer pushes new blocks, reorgs, transactions appended into pool and
+// transfers involving the subscribed addresses or assets to WebSocket clients.
+type WSServer struct {
+	port     uint16
+	store    *blockchain.TokenChainStore
+	upgrader websocket.Upgrader
+	events   chan wsEvent
+	quit     chan struct{}
+	server   *http.Server
+
+	mtx     sync.Mutex
+	clients map[*wsClient]struct{}
+}
+
+// OnBlockPersisted implements blockchain.BlockListener.
+func (s *WSServer) OnBlockPersisted(b *types.Block) {
+	s.queueEvent(wsEvent{kind: wsBlockPersisted, block: b})
+}
+
+// OnBlockRolledBack implements blockchain.BlockListener.
+func (s *WSServer) OnBlockRolledBack(b *types.Block) {
+	s.queueEvent(wsEvent{kind: wsBlockRolledBack, block: b})
+}
+
+// OnTxAdded is the listener of transactions appended into pool.
+func (s *WSServer) OnTxAdded(tx *types.Transaction) {
+	s.queueEvent(wsEvent{kind: wsTxAdded, tx: tx})
+}
+
+func (s *WSServer) queueEvent(event wsEvent) {
+	select {
+	case s.events <- event:
+	default:
+	}
+}
+
+func (s *WSServer) eventHandler() {
+	for {
+		select {
+		case event := <-s.events:
+			s.handleEvent(&event)
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *WSServer) handleEvent(event *wsEvent) {
+	s.mtx.Lock()
+	clients := make([]*wsClient, 0, len(s.clients))
+	for client := range s.clients {
+		clients = append(clients, client)
+	}
+	s.mtx.Unlock()
+	if len(clients) == 0 {
+		return
+	}
+
+	subs := make([]wsSubscription, len(clients))
+	watching := false
+	for i, client := range clients {
+		subs[i] = client.subscription()
+		watching = watching || subs[i].watching()
+	}
+
+	switch event.kind {
+	case wsBlockPersisted, wsBlockRolledBack:
+		topic, name, status := TopicBlocks, "block", TransferConfirmed
+		if event.kind == wsBlockRolledBack {
+			topic, name, status = TopicReorgs, "reorg", TransferRolledBack
+		}
+		header := event.block.Header
+		blockHash := header.Hash()
+		data := BlockNotification{
+			Hash:   service.ToReversedString(blockHash),
+			Height: header.Height,
+			Time:   header.Timestamp,
+			Tx:     make([]string, 0, len(event.block.Transactions)),
+		}
+		for _, tx := range event.block.Transactions {
+			data.Tx = append(data.Tx, service.ToReversedString(tx.Hash()))
+		}
+		for i, client := range clients {
+			if _, ok := subs[i].topics[topic]; ok {
+				client.notify(name, data)
+			}
+		}
+		if !watching {
+			return
+		}
+		for _, tx := range event.block.Transactions {
+			s.notifyTransfer(clients, subs, tx, &TransferNotification{
+				Status:    status,
+				BlockHash: data.Hash,
+				Height:    header.Height,
+			})
+		}
+
+	case wsTxAdded:
+		data := MempoolNotification{
+			TxID: service.ToReversedString(event.tx.Hash()),
+			Type: byte(event.tx.TxType),
+			Size: event.tx.GetSize(),
+		}
+		for i, client := range clients {
+			if _, ok := subs[i].topics[TopicMempool]; ok {
+				client.notify("mempool", data)
+			}
+		}
+		if !watching {
+			return
+		}
+		s.notifyTransfer(clients, subs, event.tx,
+			&TransferNotification{Status: TransferPending})
+	}
+}
+
+// notifyTransfer pushes the transfer event to the clients subscribed the
+// addresses or assets involved in the transaction.
+func (s *WSServer) notifyTransfer(clients []*wsClient, subs []wsSubscription,
+	tx *types.Transaction, data *TransferNotification) {
+	inv := s.involvement(tx)
+	data.TxID = service.ToReversedString(tx.Hash())
+	for i, client := range clients {
+		if !subs[i].watching() {
+			continue
+		}
+		addresses, assets := subs[i].involved(inv)
+		if len(addresses) == 0 && len(assets) == 0 {
+			continue
+		}
+		notification := *data
+		notification.Addresses = addresses
+		notification.Assets = assets
+		client.notify("transfer", notification)
+	}
+}
+
+// involvement collects the addresses and assets of the outputs and the
+// referenced outputs of the transaction, and the asset registered by it.
+func (s *WSServer) involvement(tx *types.Transaction) *involvement {
+	inv := &involvement{
+		programHashes: make(map[Uint168]struct{}),
+		assetIDs:      make(map[Uint256]struct{}),
+	}
+	add := func(output *types.Output) {
+		inv.programHashes[output.ProgramHash] = struct{}{}
+		inv.assetIDs[output.AssetID] = struct{}{}
+	}
+	for _, output := range tx.Outputs {
+		add(output)
+	}
+	for _, input := range tx.Inputs {
+		reference, _, err := s.store.GetTransaction(input.Previous.TxID)
+		if err != nil || int(input.Previous.Index) >= len(reference.Outputs) {
+			continue
+		}
+		add(reference.Outputs[input.Previous.Index])
+	}
+	if payload, ok := tx.Payload.(*types.PayloadRegisterAsset); ok {
+		inv.assetIDs[payload.Asset.Hash()] = struct{}{}
+	}
+	return inv
+}
+
+func (s *WSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	client := &wsClient{
+		conn: conn,
+		send: make(chan []byte, wsClientQueueSize),
+		quit: make(chan struct{}),
+		sub: wsSubscription{
+			topics:    make(map[string]struct{}),
+			addresses: make(map[Uint168]struct{}),
+			assets:    make(map[Uint256]struct{}),
+		},
+	}
+
+	s.mtx.Lock()
+	s.clients[client] = struct{}{}
+	s.mtx.Unlock()
+
+	go s.writeHandler(client)
+	s.readHandler(client)
+
+	s.mtx.Lock()
+	delete(s.clients, client)
+	s.mtx.Unlock()
+	client.close()
+}
+
+func (s *WSServer) readHandler(client *wsClient) {
+	conn := client.conn
+	conn.SetReadLimit(wsMaxMessageLen)
+	conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req WSRequest
+		if err := json.Unmarshal(msg, &req); err != nil {
+			client.respond(nil, nil, errors.New("invalid request"))
+			continue
+		}
+		switch req.Method {
+		case "subscribe":
+			err = client.update(&req.Params, true)
+		case "unsubscribe":
+			err = client.update(&req.Params, false)
+		default:
+			err = fmt.Errorf("unknown method %s", req.Method)
+		}
+		if err != nil {
+			client.respond(req.ID, nil, err)
+			continue
+		}
+		client.respond(req.ID, true, nil)
+	}
+}
+
+func (s *WSServer) writeHandler(client *wsClient) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	defer client.close()
+
+	conn := client.conn
+	for {
+		select {
+		case msg := <-client.send:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+
+		case <-client.quit:
+			return
+		}
+	}
+}
+
+// Start starts serving WebSocket clients, it blocks until the server stopped.
+func (s *WSServer) Start() error {
+	listener, err := net.Listen("tcp", fmt.Sprint(":", s.port))
+	if err != nil {
+		return err
+	}
+	go s.eventHandler()
+	s.server = &http.Server{Handler: s}
+	err = s.server.Serve(listener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Stop stops the WebSocket server and disconnects all clients.
+func (s *WSServer) Stop() error {
+	if s.server == nil {
+		return nil
+	}
+	close(s.quit)
+	err := s.server.Close()
+
+	s.mtx.Lock()
+	for client := range s.clients {
+		client.close()
+	}
+	s.mtx.Unlock()
+	return err
+}
+
+// checkOrigin returns the origin check of the allowed origins, "*" allows
+// any origin.  Only requests from the same host are allowed if no origin is
+// given, and requests without the Origin header, which are not sent by
+// browsers, are always allowed.
+func checkOrigin(origins []string) func(r *http.Request) bool {
+	allowed := make(map[string]struct{}, len(origins))
+	for _, origin := range origins {
+		if origin == "*" {
+			return func(r *http.Request) bool { return true }
+		}
+		allowed[strings.ToLower(strings.TrimSuffix(origin, "/"))] = struct{}{}
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if len(origin) == 0 {
+			return true
+		}
+		if _, ok := allowed[strings.ToLower(origin)]; ok {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// NewWSServer creates a WebSocket server accepting connections from the
+// allowed origins, it should be added as the listener of the chain store and
+// transaction pool before they start.
+func NewWSServer(port uint16, origins []string, store *blockchain.TokenChainStore) *WSServer {
+	return &WSServer{
+		port:  port,
+		store: store,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: checkOrigin(origins),
+		},
+		events:  make(chan wsEvent, wsEventQueueSize),
+		quit:    make(chan struct{}),
+		clients: make(map[*wsClient]struct{}),
+	}
+}
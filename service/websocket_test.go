@@ -0,0 +1,69 @@
+package service
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
+
+	"github.com/elastos/Elastos.ELA.SideChain/service"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWSRegistrationByAssetID(t *testing.T) {
+	s := NewWSServer(0, nil, nil)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	registration := &types.Transaction{
+		TxType: types.RegisterAsset,
+		Payload: &types.PayloadRegisterAsset{
+			Asset:      types.Asset{Name: "TOKEN", Precision: 4},
+			Controller: common.Uint168{0x21, 0x01},
+		},
+		Attributes: []*types.Attribute{},
+		Inputs:     []*types.Input{},
+		Outputs:    []*types.Output{},
+		Programs:   []*types.Program{},
+	}
+	assetID := service.ToReversedString(registration.Payload.(*types.PayloadRegisterAsset).Asset.Hash())
+
+	// Subscribing the hash of the registration is not subscribing the asset.
+	for _, asset := range []string{service.ToReversedString(registration.Hash()), assetID} {
+		assert.NoError(t, conn.WriteJSON(WSRequest{
+			ID:     asset,
+			Method: "subscribe",
+			Params: WSSubscribeParams{Assets: []string{asset}},
+		}))
+		var resp WSResponse
+		assert.NoError(t, conn.ReadJSON(&resp))
+		assert.Equal(t, true, resp.Result)
+	}
+
+	header := params.GenesisBlock.Header
+	header.Height = 1
+	block := &types.Block{Header: header, Transactions: []*types.Transaction{registration}}
+	s.handleEvent(&wsEvent{kind: wsBlockPersisted, block: block})
+
+	var notification struct {
+		Event string               `json:"event"`
+		Data  TransferNotification `json:"data"`
+	}
+	assert.NoError(t, conn.ReadJSON(&notification))
+	assert.Equal(t, "transfer", notification.Event)
+	assert.Equal(t, service.ToReversedString(registration.Hash()), notification.Data.TxID)
+	assert.Equal(t, TransferConfirmed, notification.Data.Status)
+	assert.Equal(t, []string{assetID}, notification.Data.Assets)
+}
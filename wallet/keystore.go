@@ -0,0 +1,281 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KeystoreVersion is the version of the keystore file format.
+	KeystoreVersion = 1
+
+	// scrypt parameters to derive the encryption key from the password.
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// ErrWrongPassword is returned if the password can not decrypt the keystore.
+var ErrWrongPassword = errors.New("wrong wallet password")
+
+// keystoreAccount is an account in the keystore file, the private key is
+// encrypted by AES-GCM with the key derived from the password.
+type keystoreAccount struct {
+	Address    string `json:"address"`
+	PublicKey  string `json:"publickey"`
+	Nonce      string `json:"nonce"`
+	PrivateKey string `json:"privatekey"`
+}
+
+// keystoreFile is the JSON format of the keystore file.
+type keystoreFile struct {
+	Version      int               `json:"version"`
+	Salt         string            `json:"salt"`
+	N            int               `json:"n"`
+	R            int               `json:"r"`
+	P            int               `json:"p"`
+	PasswordHash string            `json:"passwordhash"`
+	Accounts     []keystoreAccount `json:"accounts"`
+}
+
+// account is an address of the wallet.
+type account struct {
+	address     string
+	programHash common.Uint168
+	publicKey   *crypto.PublicKey
+	code        []byte
+}
+
+// keystore keeps the accounts of the wallet in an encrypted file.
+type keystore struct {
+	path     string
+	file     keystoreFile
+	accounts []*account
+}
+
+// deriveKey derives the encryption key from the password and returns it with
+// the password hash, the password is checked against the keystore if it is
+// not empty.
+func (ks *keystore) deriveKey(password string) ([]byte, string, error) {
+	salt, err := common.HexStringToBytes(ks.file.Salt)
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := scrypt.Key([]byte(password), salt, ks.file.N, ks.file.R, ks.file.P, scryptKeyLen)
+	if err != nil {
+		return nil, "", err
+	}
+	hash := sha256.Sum256(key)
+	passwordHash := common.BytesToHexString(hash[:])
+	if len(ks.file.PasswordHash) > 0 && ks.file.PasswordHash != passwordHash {
+		return nil, "", ErrWrongPassword
+	}
+	return key, passwordHash, nil
+}
+
+// newAccount returns the account of the public key.
+func newAccount(publicKey *crypto.PublicKey) (*account, error) {
+	ct, err := contract.CreateStandardContract(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	programHash := ct.ToProgramHash()
+	address, err := programHash.ToAddress()
+	if err != nil {
+		return nil, err
+	}
+	return &account{
+		address:     address,
+		programHash: *programHash,
+		publicKey:   publicKey,
+		code:        ct.Code,
+	}, nil
+}
+
+// newAddress generates a key pair, adds the account into the keystore and
+// saves the keystore file.  The keystore is only updated once the file is
+// saved, so the first password is not kept if the file can not be written.
+func (ks *keystore) newAddress(password string) (*account, error) {
+	key, passwordHash, err := ks.deriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, publicKey, err := crypto.GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	acc, err := newAccount(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := publicKey.EncodePoint(true)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	encrypted := gcm.Seal(nil, nonce, privateKey, pubKey)
+
+	file := ks.file
+	file.PasswordHash = passwordHash
+	file.Accounts = append(append([]keystoreAccount{}, ks.file.Accounts...), keystoreAccount{
+		Address:    acc.address,
+		PublicKey:  common.BytesToHexString(pubKey),
+		Nonce:      common.BytesToHexString(nonce),
+		PrivateKey: common.BytesToHexString(encrypted),
+	})
+	if err := ks.save(&file); err != nil {
+		return nil, err
+	}
+	ks.file = file
+	ks.accounts = append(ks.accounts, acc)
+	return acc, nil
+}
+
+// privateKeys decrypts the private keys of the accounts by program hash.
+func (ks *keystore) privateKeys(password string) (map[common.Uint168][]byte, error) {
+	if len(ks.file.PasswordHash) == 0 {
+		return nil, errors.New("wallet has no address")
+	}
+	key, _, err := ks.deriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[common.Uint168][]byte, len(ks.accounts))
+	for i, acc := range ks.file.Accounts {
+		pubKey, err := common.HexStringToBytes(acc.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		nonce, err := common.HexStringToBytes(acc.Nonce)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := common.HexStringToBytes(acc.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := gcm.Open(nil, nonce, encrypted, pubKey)
+		if err != nil {
+			return nil, ErrWrongPassword
+		}
+		keys[ks.accounts[i].programHash] = privateKey
+	}
+	return keys, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// save writes the keystore file through a temporary file, so the file is
+// never left half written.
+func (ks *keystore) save(file *keystoreFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ks.path), 0700); err != nil {
+		return err
+	}
+	tmp := ks.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ks.path)
+}
+
+// openKeystore loads the keystore file, a new keystore is returned if the
+// file does not exist.
+func openKeystore(path string) (*keystore, error) {
+	ks := &keystore{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		salt := make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+		ks.file = keystoreFile{
+			Version: KeystoreVersion,
+			Salt:    common.BytesToHexString(salt),
+			N:       scryptN,
+			R:       scryptR,
+			P:       scryptP,
+		}
+		return ks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &ks.file); err != nil {
+		return nil, fmt.Errorf("invalid keystore file, %s", err)
+	}
+	if ks.file.Version != KeystoreVersion {
+		return nil, fmt.Errorf("unknown keystore version %d", ks.file.Version)
+	}
+	for _, acc := range ks.file.Accounts {
+		pubKey, err := common.HexStringToBytes(acc.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := crypto.DecodePoint(pubKey)
+		if err != nil {
+			return nil, err
+		}
+		a, err := newAccount(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if a.address != acc.Address {
+			return nil, fmt.Errorf("address %s does not match the public key", acc.Address)
+		}
+		ks.accounts = append(ks.accounts, a)
+	}
+	return ks, nil
+}
+
+// signature returns the program parameter signing the data.
+func signature(privateKey []byte, data []byte) ([]byte, error) {
+	sig, err := crypto.Sign(privateKey, data)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteByte(byte(len(sig)))
+	buf.Write(sig)
+	return buf.Bytes(), nil
+}
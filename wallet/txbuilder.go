@@ -0,0 +1,120 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/coinselect"
+
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// CreateTransaction creates and signs a transaction paying the outputs, the
+// change is paid to the change address, or the first address of the wallet if
+// it is nil.  The ELA fee is no less than fee and covers the minimum fee rate
+// of the transaction pool.  The inputs are selected by the strategy, or the
+// default strategy of the selector if it is nil.
+func (w *Wallet) CreateTransaction(outputs []*types.Output, memos map[int][]byte, change *common.Uint168,
+	fee common.Fixed64, strategy coinselect.Strategy, password string) (*types.Transaction, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	return w.createTransaction(outputs, memos, change, fee, strategy, password)
+}
+
+func (w *Wallet) createTransaction(outputs []*types.Output, memos map[int][]byte, change *common.Uint168, fee common.Fixed64,
+	strategy coinselect.Strategy, password string) (*types.Transaction, error) {
+	if len(w.keystore.accounts) == 0 {
+		return nil, errors.New("wallet has no address")
+	}
+	keys, err := w.keystore.privateKeys(password)
+	if err != nil {
+		return nil, err
+	}
+	changeHash := w.keystore.accounts[0].programHash
+	if change != nil {
+		changeHash = *change
+	}
+
+	coins, err := w.unspents(1)
+	if err != nil {
+		return nil, err
+	}
+	result, err := w.cfg.Selector.Fund(coins, &coinselect.Request{
+		Outputs:  outputs,
+		Memos:    memos,
+		Change:   changeHash,
+		MinFee:   fee,
+		FeeRate:  w.cfg.TxPool.Info().MinFeeRate,
+		Strategy: strategy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tx, err := result.Transaction()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.sign(tx, result.Inputs, keys); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// sign signs the transaction by the keys of the input addresses.
+func (w *Wallet) sign(tx *types.Transaction, inputs []*coinselect.Coin,
+	keys map[common.Uint168][]byte) error {
+	buf := new(bytes.Buffer)
+	if err := tx.SerializeUnsigned(buf); err != nil {
+		return err
+	}
+
+	programHashes := make(map[common.Uint168]struct{})
+	for _, c := range inputs {
+		programHashes[c.ProgramHash] = struct{}{}
+	}
+	hashes := make([]common.Uint168, 0, len(programHashes))
+	for programHash := range programHashes {
+		hashes = append(hashes, programHash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+
+	accounts := w.programHashes()
+	for _, programHash := range hashes {
+		key, ok := keys[programHash]
+		if !ok {
+			return errors.New("no private key of the input address")
+		}
+		parameter, err := signature(key, buf.Bytes())
+		if err != nil {
+			return err
+		}
+		tx.Programs = append(tx.Programs, &types.Program{
+			Code:      accounts[programHash].code,
+			Parameter: parameter,
+		})
+	}
+	return nil
+}
+
+// Send creates the transaction like CreateTransaction and submits it, the
+// wallet is locked until it is submitted, so concurrent sends never spend the
+// same outputs.
+func (w *Wallet) Send(outputs []*types.Output, memos map[int][]byte, change *common.Uint168, fee common.Fixed64,
+	strategy coinselect.Strategy, password string, submit func(*types.Transaction) error) (*types.Transaction, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	tx, err := w.createTransaction(outputs, memos, change, fee, strategy, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := submit(tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
@@ -0,0 +1,130 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/coinselect"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/core"
+	mp "github.com/elastos/Elastos.ELA.SideChain.Token/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/params"
+
+	"github.com/elastos/Elastos.ELA.SideChain/mempool"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTransactionValid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	chainParams := &params.MainNetParams
+	store, err := blockchain.NewChainStore(params.GenesisBlock, chainParams.ElaAssetId,
+		&params.MainNetAssetParams, filepath.Join(dir, "chain"), true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+
+	mempoolCfg := mp.Config{
+		ChainParams: chainParams,
+		ChainStore:  store.ChainStore,
+		AssetParams: &params.MainNetAssetParams,
+		Policy:      &mp.Policy{},
+	}
+	mempoolCfg.FeeHelper = mp.NewFeeHelper(&mempoolCfg)
+	validator := mp.NewValidator(&mempoolCfg)
+	txPool := mp.NewTxPool(&mp.Config{ChainParams: chainParams, ChainStore: store.ChainStore},
+		mempool.New(&mempool.Config{
+			ChainParams: chainParams,
+			ChainStore:  store.ChainStore,
+			Validator:   validator,
+			FeeHelper:   mempoolCfg.FeeHelper.FeeHelper,
+		}))
+
+	w, err := Open(&Config{
+		Path:        filepath.Join(dir, "wallet.json"),
+		Store:       store,
+		TxPool:      txPool,
+		ChainParams: chainParams,
+		Selector:    coinselect.New(&coinselect.Config{Store: store, ChainParams: chainParams}),
+	})
+	assert.NoError(t, err)
+	address, err := w.NewAddress("password")
+	assert.NoError(t, err)
+	programHash, err := common.Uint168FromAddress(address)
+	assert.NoError(t, err)
+
+	// Register a token of precision 4 and fund the wallet with the token and
+	// ELA in the next block.
+	register := &types.Transaction{
+		TxType: types.RegisterAsset,
+		Payload: &types.PayloadRegisterAsset{
+			Asset:      types.Asset{Name: "TOKEN", Precision: 4},
+			Controller: *programHash,
+		},
+		Attributes: []*types.Attribute{},
+		Inputs:     []*types.Input{},
+		Programs:   []*types.Program{},
+	}
+	assetID := register.Payload.(*types.PayloadRegisterAsset).Asset.Hash()
+	tokens, _ := core.ParseTokenAmount("100", 4)
+	register.Outputs = []*types.Output{{AssetID: assetID, TokenValue: *tokens.Int(), ProgramHash: *programHash}}
+	funding := &types.Transaction{
+		TxType:     types.TransferAsset,
+		Payload:    &types.PayloadTransferAsset{},
+		Attributes: []*types.Attribute{},
+		Inputs:     []*types.Input{},
+		Outputs: []*types.Output{{
+			AssetID:     chainParams.ElaAssetId,
+			Value:       10 * 100000000,
+			ProgramHash: *programHash,
+		}},
+		Programs: []*types.Program{},
+	}
+	header := params.GenesisBlock.Header
+	header.Height = 1
+	header.Previous = params.GenesisBlock.Hash()
+	assert.NoError(t, store.SaveBlock(&types.Block{
+		Header:       header,
+		Transactions: []*types.Transaction{register, funding},
+	}))
+
+	// Send 1.5 tokens, the change of 98.5 tokens keeps the precision of the
+	// asset and the ELA change leaves the fee.
+	amount, _ := core.ParseTokenAmount("1.5", 4)
+	to := common.Uint168{0x21, 0x01}
+	tx, err := w.CreateTransaction([]*types.Output{{
+		AssetID:     assetID,
+		TokenValue:  *amount.Int(),
+		ProgramHash: to,
+	}}, nil, nil, 0, nil, "password")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, len(tx.Programs))
+
+	tokenOut, elaOut := new(big.Int), common.Fixed64(0)
+	for _, output := range tx.Outputs {
+		if output.AssetID.IsEqual(chainParams.ElaAssetId) {
+			elaOut += output.Value
+		} else {
+			tokenOut.Add(tokenOut, &output.TokenValue)
+		}
+	}
+	assert.Equal(t, tokens.Int(), tokenOut)
+	assert.True(t, int64(10*100000000-elaOut) >= chainParams.MinTransactionFee)
+
+	assert.NoError(t, validator.CheckTransactionSanity(tx))
+	assert.NoError(t, validator.CheckTransactionContext(tx))
+
+	// The validator rejects an output out of the precision of the asset.
+	tx.Outputs[0].TokenValue.Add(&tx.Outputs[0].TokenValue, big.NewInt(1))
+	assert.Error(t, validator.CheckTransactionSanity(tx))
+}
@@ -0,0 +1,315 @@
+package wallet
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA.SideChain.Token/blockchain"
+	"github.com/elastos/Elastos.ELA.SideChain.Token/coinselect"
+	mp "github.com/elastos/Elastos.ELA.SideChain.Token/mempool"
+
+	"github.com/elastos/Elastos.ELA.SideChain/config"
+	"github.com/elastos/Elastos.ELA.SideChain/types"
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// Config is the configuration of the wallet.
+type Config struct {
+	// Path is the path of the keystore file.
+	Path string
+
+	Store       *blockchain.TokenChainStore
+	TxPool      *mp.TxPool
+	ChainParams *config.Params
+
+	// Selector selects the inputs of transactions of the wallet.
+	Selector *coinselect.Selector
+}
+
+// Balance is the balance of an asset in base units, ELA values are counted in
+// the smallest unit of Fixed64.
+type Balance struct {
+	// Spendable is the value of confirmed outputs that can be spent.
+	Spendable *big.Int
+
+	// Locked is the value of outputs locked by OutputLock or coinbase
+	// maturity.
+	Locked *big.Int
+
+	// Pending is the value of outputs of transactions in pool.
+	Pending *big.Int
+}
+
+// TxRecord is a transaction involving the wallet.
+type TxRecord struct {
+	TxID    common.Uint256
+	Height  uint32
+	Pending bool
+
+	// Amounts are the values by asset the wallet received, negative if the
+	// wallet spent more than received.
+	Amounts map[common.Uint256]*big.Int
+
+	// Fee is the ELA fee of the transaction, it is nil if the wallet did not
+	// fund all the inputs.
+	Fee *big.Int
+}
+
+// Wallet keeps the keys of the addresses, and creates and signs transactions
+// spending their outputs.
+type Wallet struct {
+	cfg Config
+
+	mtx      sync.Mutex
+	keystore *keystore
+}
+
+// NewAddress generates a new address of the wallet, the password of the first
+// address becomes the password of the wallet.
+func (w *Wallet) NewAddress(password string) (string, error) {
+	if len(password) == 0 {
+		return "", errors.New("password can not be empty")
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	acc, err := w.keystore.newAddress(password)
+	if err != nil {
+		return "", err
+	}
+	return acc.address, nil
+}
+
+// Addresses returns the addresses of the wallet.
+func (w *Wallet) Addresses() []string {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	addresses := make([]string, 0, len(w.keystore.accounts))
+	for _, acc := range w.keystore.accounts {
+		addresses = append(addresses, acc.address)
+	}
+	return addresses
+}
+
+// programHashes returns the program hashes of the wallet addresses.
+func (w *Wallet) programHashes() map[common.Uint168]*account {
+	programHashes := make(map[common.Uint168]*account, len(w.keystore.accounts))
+	for _, acc := range w.keystore.accounts {
+		programHashes[acc.programHash] = acc
+	}
+	return programHashes
+}
+
+// outputValue returns the value of the output in base units.
+func (w *Wallet) outputValue(output *types.Output) *big.Int {
+	if output.AssetID.IsEqual(w.cfg.ChainParams.ElaAssetId) {
+		return big.NewInt(int64(output.Value))
+	}
+	return new(big.Int).Set(&output.TokenValue)
+}
+
+// poolSpent returns the outpoints spent by transactions in pool.
+func (w *Wallet) poolSpent() map[types.OutPoint]struct{} {
+	spent := make(map[types.OutPoint]struct{})
+	for _, tx := range w.cfg.TxPool.GetTxsInPool() {
+		for _, input := range tx.Inputs {
+			spent[input.Previous] = struct{}{}
+		}
+	}
+	return spent
+}
+
+// unspents returns the unspent outputs of the wallet not spent by
+// transactions in pool, with at least minConf confirmations.
+func (w *Wallet) unspents(minConf uint32) ([]*coinselect.Coin, error) {
+	bestHeight := w.cfg.Store.GetHeight()
+	spent := w.poolSpent()
+
+	var unspents []*coinselect.Coin
+	for programHash := range w.programHashes() {
+		coins, err := w.cfg.Selector.Coins(programHash)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range coins {
+			if _, ok := spent[c.OutPoint]; ok {
+				continue
+			}
+			if c.Height > bestHeight || bestHeight-c.Height+1 < minConf {
+				continue
+			}
+			unspents = append(unspents, c)
+		}
+	}
+	return unspents, nil
+}
+
+// Balances returns the balances of the wallet by asset, outputs with less
+// than minConf confirmations are not spendable.
+func (w *Wallet) Balances(minConf uint32) (map[common.Uint256]*Balance, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	balances := make(map[common.Uint256]*Balance)
+	balance := func(assetID common.Uint256) *Balance {
+		b, ok := balances[assetID]
+		if !ok {
+			b = &Balance{Spendable: new(big.Int), Locked: new(big.Int), Pending: new(big.Int)}
+			balances[assetID] = b
+		}
+		return b
+	}
+
+	unspents, err := w.unspents(minConf)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range unspents {
+		b := balance(c.AssetID)
+		if c.Locked {
+			b.Locked.Add(b.Locked, c.Value)
+		} else {
+			b.Spendable.Add(b.Spendable, c.Value)
+		}
+	}
+
+	programHashes := w.programHashes()
+	for _, tx := range w.cfg.TxPool.GetTxsInPool() {
+		for _, output := range tx.Outputs {
+			if _, ok := programHashes[output.ProgramHash]; ok {
+				b := balance(output.AssetID)
+				b.Pending.Add(b.Pending, w.outputValue(output))
+			}
+		}
+	}
+	return balances, nil
+}
+
+// record returns the record of the transaction, or nil if it does not
+// involve the wallet.
+func (w *Wallet) record(tx *types.Transaction, programHashes map[common.Uint168]*account) *TxRecord {
+	rec := &TxRecord{TxID: tx.Hash(), Amounts: make(map[common.Uint256]*big.Int)}
+	amount := func(assetID common.Uint256) *big.Int {
+		a, ok := rec.Amounts[assetID]
+		if !ok {
+			a = new(big.Int)
+			rec.Amounts[assetID] = a
+		}
+		return a
+	}
+
+	involved, funded := false, len(tx.Inputs) > 0 && !tx.IsCoinBaseTx()
+	elaIn, elaOut := new(big.Int), new(big.Int)
+	for _, input := range tx.Inputs {
+		if tx.IsCoinBaseTx() {
+			break
+		}
+		reference, _, err := w.cfg.Store.GetTransaction(input.Previous.TxID)
+		if err != nil || int(input.Previous.Index) >= len(reference.Outputs) {
+			funded = false
+			continue
+		}
+		output := reference.Outputs[input.Previous.Index]
+		if output.AssetID.IsEqual(w.cfg.ChainParams.ElaAssetId) {
+			elaIn.Add(elaIn, w.outputValue(output))
+		}
+		if _, ok := programHashes[output.ProgramHash]; !ok {
+			funded = false
+			continue
+		}
+		involved = true
+		a := amount(output.AssetID)
+		a.Sub(a, w.outputValue(output))
+	}
+	for _, output := range tx.Outputs {
+		if output.AssetID.IsEqual(w.cfg.ChainParams.ElaAssetId) {
+			elaOut.Add(elaOut, w.outputValue(output))
+		}
+		if _, ok := programHashes[output.ProgramHash]; !ok {
+			continue
+		}
+		involved = true
+		a := amount(output.AssetID)
+		a.Add(a, w.outputValue(output))
+	}
+	if !involved {
+		return nil
+	}
+	if funded {
+		rec.Fee = elaIn.Sub(elaIn, elaOut)
+	}
+	return rec
+}
+
+// Transactions returns the transactions involving the wallet, pending ones
+// first and then the confirmed ones from the newest.  The first skip records
+// are skipped and no more than count records are returned.
+func (w *Wallet) Transactions(skip, count int) ([]*TxRecord, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	programHashes := w.programHashes()
+
+	var pending []*TxRecord
+	for _, tx := range w.cfg.TxPool.GetTxsInPool() {
+		if rec := w.record(tx, programHashes); rec != nil {
+			rec.Pending = true
+			pending = append(pending, rec)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].TxID.String() < pending[j].TxID.String()
+	})
+
+	heights := make(map[common.Uint256]uint32)
+	for programHash := range programHashes {
+		txs, err := w.cfg.Store.GetAddressTxs(programHash)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range txs {
+			heights[tx.TxID] = tx.Height
+		}
+	}
+	confirmed := make([]blockchain.AddressTx, 0, len(heights))
+	for txID, height := range heights {
+		confirmed = append(confirmed, blockchain.AddressTx{TxID: txID, Height: height})
+	}
+	sort.Slice(confirmed, func(i, j int) bool {
+		if confirmed[i].Height != confirmed[j].Height {
+			return confirmed[i].Height > confirmed[j].Height
+		}
+		return confirmed[i].TxID.String() < confirmed[j].TxID.String()
+	})
+
+	records := make([]*TxRecord, 0, count)
+	for i := skip; i < len(pending)+len(confirmed) && len(records) < count; i++ {
+		if i < len(pending) {
+			records = append(records, pending[i])
+			continue
+		}
+		addressTx := confirmed[i-len(pending)]
+		tx, _, err := w.cfg.Store.GetTransaction(addressTx.TxID)
+		if err != nil {
+			return nil, err
+		}
+		if rec := w.record(tx, programHashes); rec != nil {
+			rec.Height = addressTx.Height
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+// Open opens the wallet of the keystore file, a new keystore file is created
+// when the first address is generated if it does not exist.
+func Open(cfg *Config) (*Wallet, error) {
+	ks, err := openKeystore(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{cfg: *cfg, keystore: ks}, nil
+}
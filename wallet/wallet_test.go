@@ -0,0 +1,51 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeystore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "wallet.json")
+
+	ks, err := openKeystore(path)
+	assert.NoError(t, err)
+	_, err = ks.privateKeys("password")
+	assert.Error(t, err)
+
+	// The password is not kept if the keystore can not be saved.
+	blocker := filepath.Join(dir, "blocker")
+	assert.NoError(t, ioutil.WriteFile(blocker, nil, 0600))
+	ks.path = filepath.Join(blocker, "wallet.json")
+	_, err = ks.newAddress("other")
+	assert.Error(t, err)
+	assert.Empty(t, ks.file.PasswordHash)
+	assert.Empty(t, ks.file.Accounts)
+	ks.path = path
+
+	acc, err := ks.newAddress("password")
+	assert.NoError(t, err)
+	_, err = ks.newAddress("wrong")
+	assert.Equal(t, ErrWrongPassword, err)
+	assert.Equal(t, 1, len(ks.accounts))
+
+	ks, err = openKeystore(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(ks.accounts))
+	assert.Equal(t, acc.address, ks.accounts[0].address)
+
+	_, err = ks.privateKeys("wrong")
+	assert.Equal(t, ErrWrongPassword, err)
+	keys, err := ks.privateKeys("password")
+	assert.NoError(t, err)
+	assert.Contains(t, keys, acc.programHash)
+	_, err = signature(keys[acc.programHash], []byte("data"))
+	assert.NoError(t, err)
+}